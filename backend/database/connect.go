@@ -2,17 +2,30 @@ package database
 
 import (
 	"database/sql"
+	"strings"
 	"sync"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 var (
-	db *sql.DB
-	mu sync.RWMutex
+	db     *sql.DB
+	driver Driver
+	mu     sync.RWMutex
 )
 
-func Init(dbPath string) error {
+// Init opens the database and applies all pending migrations.
+func Init(d Driver, dsn string) error {
+	if err := Open(d, dsn); err != nil {
+		return err
+	}
+	_, err := MigrateUp()
+	return err
+}
+
+// Open opens the database without touching the schema.
+func Open(d Driver, dsn string) error {
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -24,10 +37,15 @@ func Init(dbPath string) error {
 	}
 
 	var err error
-	db, err = sql.Open("sqlite3", dbPath)
+	if d == DriverSQLite {
+		dsn = withImmediateTxLock(dsn)
+	}
+
+	db, err = sql.Open(string(d), dsn)
 	if err != nil {
 		return err
 	}
+	driver = d
 
 	db.SetMaxOpenConns(10)
 	db.SetMaxIdleConns(5)
@@ -38,7 +56,7 @@ func Init(dbPath string) error {
 		return err
 	}
 
-	return createTables()
+	return nil
 }
 
 func GetDB() *sql.DB {
@@ -47,6 +65,12 @@ func GetDB() *sql.DB {
 	return db
 }
 
+func GetDriver() Driver {
+	mu.RLock()
+	defer mu.RUnlock()
+	return driver
+}
+
 func Close() error {
 	mu.Lock()
 	defer mu.Unlock()
@@ -59,24 +83,14 @@ func Close() error {
 	return nil
 }
 
-func createTables() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS reservations (
-		id TEXT PRIMARY KEY,
-		first_name TEXT,
-		last_name TEXT NOT NULL,
-		amount INTEGER NOT NULL,
-		phone_number TEXT NOT NULL,
-		email TEXT NOT NULL,
-		created_at DATETIME NOT NULL,
-		reserve_at DATETIME NOT NULL,
-		status TEXT NOT NULL,
-		notes TEXT
-	);
-	CREATE INDEX IF NOT EXISTS idx_reserve_at ON reservations(reserve_at);
-	CREATE INDEX IF NOT EXISTS idx_status ON reservations(status);
-	CREATE INDEX IF NOT EXISTS idx_last_name ON reservations(last_name);
-	`
-	_, err := db.Exec(schema)
-	return err
+// withImmediateTxLock makes every SQLite transaction take the write lock up
+// front, so read-check-write sequences cannot interleave.
+func withImmediateTxLock(dsn string) string {
+	if strings.Contains(dsn, "_txlock=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_txlock=immediate"
+	}
+	return dsn + "?_txlock=immediate"
 }
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Driver string
+
+const (
+	DriverSQLite   Driver = "sqlite3"
+	DriverPostgres Driver = "postgres"
+)
+
+// ParseDriver maps the DB_DRIVER setting onto a supported driver.
+func ParseDriver(name string) (Driver, error) {
+	switch strings.ToLower(name) {
+	case "", "sqlite", "sqlite3":
+		return DriverSQLite, nil
+	case "postgres", "postgresql":
+		return DriverPostgres, nil
+	default:
+		return "", fmt.Errorf("unsupported database driver %q", name)
+	}
+}
+
+// Rebind rewrites `?` placeholders into the driver's placeholder syntax.
+func (d Driver) Rebind(query string) string {
+	if d != DriverPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	inString := false
+	for _, c := range query {
+		switch {
+		case c == '\'':
+			inString = !inString
+			b.WriteRune(c)
+		case c == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// Like returns the case-insensitive LIKE operator of the driver.
+func (d Driver) Like() string {
+	if d == DriverPostgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// LockTable returns the statement that serialises writers on table for the
+// rest of the transaction. SQLite needs none because its transactions are
+// opened with BEGIN IMMEDIATE.
+func (d Driver) LockTable(table string) string {
+	if d == DriverPostgres {
+		return "LOCK TABLE " + table + " IN SHARE ROW EXCLUSIVE MODE"
+	}
+	return ""
+}
+
+func (d Driver) migrationDir() string {
+	if d == DriverPostgres {
+		return "postgres"
+	}
+	return "sqlite"
+}
+
+func (d Driver) timestampType() string {
+	if d == DriverPostgres {
+		return "TIMESTAMPTZ"
+	}
+	return "DATETIME"
+}
@@ -0,0 +1,285 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+func loadMigrations(d Driver) ([]Migration, error) {
+	dir := path.Join("migrations", d.migrationDir())
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionPart, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+		version, err := strconv.Atoi(versionPart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %v", fileName, err)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func ensureMigrationTable(conn *sql.DB, d Driver) error {
+	_, err := conn.Exec(fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at %s NOT NULL
+	)`, d.timestampType()))
+	return err
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+func appliedMigrations(conn *sql.DB) (map[int]appliedMigration, error) {
+	rows, err := conn.Query(`SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// verifyChecksums makes sure no migration that already ran has been edited
+// afterwards, and that the database is not ahead of the binary.
+func verifyChecksums(migrations []Migration, applied map[int]appliedMigration) error {
+	known := map[int]bool{}
+	for _, m := range migrations {
+		known[m.Version] = true
+		a, ok := applied[m.Version]
+		if !ok {
+			continue
+		}
+		if a.checksum != m.Checksum {
+			return fmt.Errorf("checksum mismatch for migration %d (%s): applied %s, file %s", m.Version, m.Name, a.checksum, m.Checksum)
+		}
+	}
+	for version, a := range applied {
+		if !known[version] {
+			return fmt.Errorf("database has unknown migration %d (%s) applied", version, a.name)
+		}
+	}
+	return nil
+}
+
+func prepareMigrations(conn *sql.DB, d Driver) ([]Migration, map[int]appliedMigration, error) {
+	if err := ensureMigrationTable(conn, d); err != nil {
+		return nil, nil, err
+	}
+	migrations, err := loadMigrations(d)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return nil, nil, err
+	}
+	return migrations, applied, nil
+}
+
+func migrateUp(conn *sql.DB, d Driver) ([]Migration, error) {
+	migrations, applied, err := prepareMigrations(conn, d)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := runInTx(conn, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec(d.Rebind(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`), m.Version, m.Name, m.Checksum, time.Now())
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+func migrateDown(conn *sql.DB, d Driver, steps int) ([]Migration, error) {
+	migrations, applied, err := prepareMigrations(conn, d)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for i := len(migrations) - 1; i >= 0 && len(ran) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return ran, fmt.Errorf("migration %d (%s) has no down script", m.Version, m.Name)
+		}
+		err := runInTx(conn, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(d.Rebind(`DELETE FROM schema_migrations WHERE version = ?`), m.Version)
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("rollback of migration %d (%s) failed: %v", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+func runInTx(conn *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// MigrateUp applies every pending migration and returns the ones that ran.
+func MigrateUp() ([]Migration, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return migrateUp(db, driver)
+}
+
+// MigrateDown rolls back the given number of most recently applied migrations.
+func MigrateDown(steps int) ([]Migration, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return migrateDown(db, driver, steps)
+}
+
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	migrations, applied, err := prepareMigrations(db, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_last_name;
+DROP INDEX IF EXISTS idx_status;
+DROP INDEX IF EXISTS idx_reserve_at;
+DROP TABLE IF EXISTS reservations;
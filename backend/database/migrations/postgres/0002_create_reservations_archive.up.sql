@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS reservations_archive (
+	id TEXT PRIMARY KEY,
+	first_name TEXT,
+	last_name TEXT NOT NULL,
+	amount INTEGER NOT NULL,
+	phone_number TEXT NOT NULL,
+	email TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	reserve_at TIMESTAMPTZ NOT NULL,
+	status TEXT NOT NULL,
+	notes TEXT,
+	anonymized BOOLEAN NOT NULL DEFAULT FALSE,
+	archived_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_archive_reserve_at ON reservations_archive(reserve_at);
+CREATE INDEX IF NOT EXISTS idx_archive_last_name ON reservations_archive(last_name);
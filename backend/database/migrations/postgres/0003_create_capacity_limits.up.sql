@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS capacity_limits (
+	id SERIAL PRIMARY KEY,
+	weekday INTEGER,
+	slot_time TEXT,
+	max_covers INTEGER NOT NULL,
+	max_reservations INTEGER NOT NULL,
+	UNIQUE (weekday, slot_time)
+);
+INSERT INTO capacity_limits (weekday, slot_time, max_covers, max_reservations) VALUES (NULL, NULL, 40, 12);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS closures (
+	id SERIAL PRIMARY KEY,
+	starts_on TEXT NOT NULL,
+	ends_on TEXT NOT NULL,
+	service_period TEXT,
+	reason TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_closures_range ON closures(starts_on, ends_on);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS reservation_status_transitions (
+	id SERIAL PRIMARY KEY,
+	reservation_id TEXT NOT NULL,
+	from_status TEXT NOT NULL,
+	to_status TEXT NOT NULL,
+	actor_role TEXT NOT NULL,
+	actor_id TEXT NOT NULL,
+	reason TEXT,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_status_transitions_reservation ON reservation_status_transitions(reservation_id);
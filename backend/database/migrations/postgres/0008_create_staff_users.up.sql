@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS staff_users (
+	id TEXT PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE,
+	email TEXT NOT NULL,
+	display_name TEXT NOT NULL,
+	role TEXT NOT NULL,
+	password_hash TEXT,
+	disabled BOOLEAN NOT NULL DEFAULT FALSE,
+	invite_token_hash TEXT UNIQUE,
+	invite_expires_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_session_tokens_session;
+DROP TABLE IF EXISTS session_tokens;
+DROP INDEX IF EXISTS idx_refresh_tokens_session;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP INDEX IF EXISTS idx_sessions_subject;
+DROP TABLE IF EXISTS sessions;
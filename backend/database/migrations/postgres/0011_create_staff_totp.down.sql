@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS security_settings;
+DROP INDEX IF EXISTS idx_staff_recovery_codes_staff;
+DROP TABLE IF EXISTS staff_recovery_codes;
+DROP TABLE IF EXISTS staff_totp;
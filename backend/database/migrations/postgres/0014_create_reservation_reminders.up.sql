@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS reservation_reminders (
+	reservation_id TEXT NOT NULL,
+	offset_minutes INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	token_hash TEXT UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	sent_at TIMESTAMPTZ,
+	responded_at TIMESTAMPTZ,
+	response TEXT,
+	PRIMARY KEY (reservation_id, offset_minutes)
+);
+CREATE INDEX IF NOT EXISTS idx_reservation_reminders_expires ON reservation_reminders(expires_at);
@@ -0,0 +1,2 @@
+ALTER TABLE email_outbox DROP COLUMN channel;
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+	reservation_id TEXT NOT NULL,
+	channel TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL,
+	PRIMARY KEY (reservation_id, channel)
+);
+ALTER TABLE email_outbox ADD COLUMN channel TEXT NOT NULL DEFAULT 'EMAIL';
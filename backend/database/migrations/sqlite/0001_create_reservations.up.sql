@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS reservations (
+	id TEXT PRIMARY KEY,
+	first_name TEXT,
+	last_name TEXT NOT NULL,
+	amount INTEGER NOT NULL,
+	phone_number TEXT NOT NULL,
+	email TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	reserve_at DATETIME NOT NULL,
+	status TEXT NOT NULL,
+	notes TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_reserve_at ON reservations(reserve_at);
+CREATE INDEX IF NOT EXISTS idx_status ON reservations(status);
+CREATE INDEX IF NOT EXISTS idx_last_name ON reservations(last_name);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_archive_last_name;
+DROP INDEX IF EXISTS idx_archive_reserve_at;
+DROP TABLE IF EXISTS reservations_archive;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS capacity_limits;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_service_periods_weekday;
+DROP TABLE IF EXISTS service_periods;
+DROP TABLE IF EXISTS schedule_settings;
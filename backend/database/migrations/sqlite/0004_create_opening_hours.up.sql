@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS schedule_settings (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	timezone TEXT NOT NULL,
+	slot_minutes INTEGER NOT NULL
+);
+INSERT INTO schedule_settings (id, timezone, slot_minutes) VALUES (1, 'Europe/Berlin', 30);
+
+CREATE TABLE IF NOT EXISTS service_periods (
+	id INTEGER PRIMARY KEY,
+	weekday INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	opens_at TEXT NOT NULL,
+	closes_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_service_periods_weekday ON service_periods(weekday);
+INSERT INTO service_periods (weekday, name, opens_at, closes_at) VALUES
+	(0, 'Abendessen', '17:00', '22:30'),
+	(1, 'Abendessen', '17:00', '22:30'),
+	(2, 'Abendessen', '17:00', '22:30'),
+	(3, 'Abendessen', '17:00', '22:30'),
+	(4, 'Abendessen', '17:00', '22:30'),
+	(5, 'Abendessen', '17:00', '22:30'),
+	(6, 'Abendessen', '17:00', '22:30');
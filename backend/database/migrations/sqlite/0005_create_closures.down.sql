@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_closures_range;
+DROP TABLE IF EXISTS closures;
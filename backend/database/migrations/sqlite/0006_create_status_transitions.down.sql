@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_status_transitions_reservation;
+DROP TABLE IF EXISTS reservation_status_transitions;
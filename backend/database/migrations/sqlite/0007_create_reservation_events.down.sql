@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS reservation_status_transitions (
+	id INTEGER PRIMARY KEY,
+	reservation_id TEXT NOT NULL,
+	from_status TEXT NOT NULL,
+	to_status TEXT NOT NULL,
+	actor_role TEXT NOT NULL,
+	actor_id TEXT NOT NULL,
+	reason TEXT,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_status_transitions_reservation ON reservation_status_transitions(reservation_id);
+
+INSERT INTO reservation_status_transitions (reservation_id, from_status, to_status, actor_role, actor_id, reason, created_at)
+SELECT reservation_id, json_extract(changes, '$[0].before'), json_extract(changes, '$[0].after'), actor_role, actor_id, reason, created_at
+FROM reservation_events WHERE event_type = 'STATUS_CHANGED' ORDER BY id;
+
+DROP INDEX IF EXISTS idx_reservation_events_reservation;
+DROP TABLE IF EXISTS reservation_events;
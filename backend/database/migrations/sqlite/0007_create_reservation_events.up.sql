@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS reservation_events (
+	id INTEGER PRIMARY KEY,
+	reservation_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	actor_role TEXT NOT NULL,
+	actor_id TEXT NOT NULL,
+	changes TEXT NOT NULL,
+	reason TEXT,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_reservation_events_reservation ON reservation_events(reservation_id, created_at);
+
+INSERT INTO reservation_events (reservation_id, event_type, actor_role, actor_id, changes, reason, created_at)
+SELECT reservation_id, 'STATUS_CHANGED', actor_role, actor_id,
+	json_array(json_object('field', 'status', 'before', from_status, 'after', to_status)),
+	reason, created_at
+FROM reservation_status_transitions ORDER BY id;
+
+DROP INDEX IF EXISTS idx_status_transitions_reservation;
+DROP TABLE IF EXISTS reservation_status_transitions;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_guest_login_links_expires;
+DROP TABLE IF EXISTS guest_login_links;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS guest_login_links (
+	token_hash TEXT PRIMARY KEY,
+	email TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_guest_login_links_expires ON guest_login_links(expires_at);
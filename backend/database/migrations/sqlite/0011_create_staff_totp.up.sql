@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS staff_totp (
+	staff_id TEXT PRIMARY KEY,
+	secret TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL DEFAULT 0,
+	last_step INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS staff_recovery_codes (
+	code_hash TEXT PRIMARY KEY,
+	staff_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	used_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_staff_recovery_codes_staff ON staff_recovery_codes(staff_id);
+
+CREATE TABLE IF NOT EXISTS security_settings (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	require_totp BOOLEAN NOT NULL DEFAULT 0
+);
+INSERT INTO security_settings (id, require_totp) VALUES (1, 0);
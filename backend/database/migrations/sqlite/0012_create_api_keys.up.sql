@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	created_by TEXT,
+	created_at DATETIME NOT NULL,
+	last_used_at DATETIME,
+	revoked_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+	api_key_id TEXT NOT NULL,
+	operation TEXT NOT NULL,
+	count INTEGER NOT NULL DEFAULT 0,
+	last_used_at DATETIME NOT NULL,
+	PRIMARY KEY (api_key_id, operation)
+);
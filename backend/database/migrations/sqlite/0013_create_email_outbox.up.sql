@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS email_outbox (
+	id TEXT PRIMARY KEY,
+	reservation_id TEXT NOT NULL,
+	event TEXT NOT NULL,
+	recipient TEXT NOT NULL,
+	custom_html TEXT,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	next_attempt_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL,
+	sent_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox(status, next_attempt_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reservation_reminders_expires;
+DROP TABLE IF EXISTS reservation_reminders;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS reservation_reminders (
+	reservation_id TEXT NOT NULL,
+	offset_minutes INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	token_hash TEXT UNIQUE,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	sent_at DATETIME,
+	responded_at DATETIME,
+	response TEXT,
+	PRIMARY KEY (reservation_id, offset_minutes)
+);
+CREATE INDEX IF NOT EXISTS idx_reservation_reminders_expires ON reservation_reminders(expires_at);
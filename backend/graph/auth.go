@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"revervation/backend/graph/model"
+	"revervation/backend/repository"
+	"slices"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// rootTypes are the operation types whose fields must all carry an @auth rule.
+var rootTypes = []string{"Query", "Mutation", "Subscription"}
+
+// AuthError is returned by the @auth directive. Authenticated tells a missing
+// login apart from a role that is too low.
+type AuthError struct {
+	Required      model.Role
+	Authenticated bool
+}
+
+func (e *AuthError) Error() string {
+	if !e.Authenticated {
+		return "Unauthenticated"
+	}
+	return "Forbidden"
+}
+
+var staffRoleFor = map[model.Role]model.StaffRole{
+	model.RoleHost:    model.StaffRoleHost,
+	model.RoleManager: model.StaffRoleManager,
+	model.RoleOwner:   model.StaffRoleOwner,
+}
+
+// grantableRoles are the requirements an API key scope may cover. Fields for
+// managers and owners stay reserved for people.
+var grantableRoles = []model.Role{model.RolePublic, model.RoleGuest, model.RoleReservationOwner, model.RoleHost}
+
+// Auth implements the @auth directive. API keys are checked against their
+// scopes only, for public fields too.
+func Auth(ctx context.Context, obj any, next graphql.Resolver, requires model.Role) (any, error) {
+	user := repository.ForContext(ctx)
+	if user != nil && user.APIKeyID != "" {
+		if user.HasScope(operationOf(graphql.GetFieldContext(ctx))) {
+			return next(ctx)
+		}
+		return nil, &AuthError{Required: requires, Authenticated: true}
+	}
+
+	if requires == model.RolePublic {
+		return next(ctx)
+	}
+	if user == nil {
+		return nil, &AuthError{Required: requires}
+	}
+
+	switch requires {
+	case model.RoleReservationOwner:
+		if user.IsAdmin {
+			return next(ctx)
+		}
+		if id, ok := reservationIDOf(ctx, obj); ok && user.OwnsReservation(id) {
+			return next(ctx)
+		}
+	case model.RoleGuest:
+		return next(ctx)
+	default:
+		role, ok := staffRoleFor[requires]
+		if !ok {
+			return nil, fmt.Errorf("unknown role %s", requires)
+		}
+		if user.IsAdmin && user.HasRole(role) {
+			return next(ctx)
+		}
+	}
+	return nil, &AuthError{Required: requires, Authenticated: true}
+}
+
+// reservationIDOf finds the reservation a field is about: the parent object
+// for fields of Reservation, otherwise the id or input.id argument.
+func reservationIDOf(ctx context.Context, obj any) (string, bool) {
+	if reservation, ok := obj.(*model.Reservation); ok {
+		return reservation.ID, true
+	}
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil {
+		return "", false
+	}
+	if id, ok := fc.Args["id"].(string); ok {
+		return id, true
+	}
+	if input, ok := fc.Args["input"].(model.UpdateReservation); ok {
+		return input.ID, true
+	}
+	return "", false
+}
+
+// operationOf names a field the way API key scopes do: root fields by their
+// name, other fields as Type.field.
+func operationOf(fc *graphql.FieldContext) string {
+	if fc == nil {
+		return ""
+	}
+	if slices.Contains(rootTypes, fc.Object) {
+		return fc.Field.Name
+	}
+	return fc.Object + "." + fc.Field.Name
+}
+
+// APIKeyScopes lists the operations an API key can be granted: every field
+// with an @auth rule of HOST or below.
+func APIKeyScopes(schema *ast.Schema) []string {
+	var scopes []string
+	for _, def := range schema.Types {
+		if def.BuiltIn {
+			continue
+		}
+		for _, field := range def.Fields {
+			directive := field.Directives.ForName("auth")
+			if directive == nil {
+				continue
+			}
+			requires := directive.Arguments.ForName("requires")
+			if requires == nil || !slices.Contains(grantableRoles, model.Role(requires.Value.Raw)) {
+				continue
+			}
+			if slices.Contains(rootTypes, def.Name) {
+				scopes = append(scopes, field.Name)
+			} else {
+				scopes = append(scopes, def.Name+"."+field.Name)
+			}
+		}
+	}
+	slices.Sort(scopes)
+	return scopes
+}
+
+// RecordAPIKeyUsage counts every operation called with an API key.
+func RecordAPIKeyUsage(auth *repository.AuthService) graphql.RootFieldMiddleware {
+	return func(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+		if user := repository.ForContext(ctx); user != nil && user.APIKeyID != "" {
+			fc := graphql.GetRootFieldContext(ctx)
+			if err := auth.RecordAPIKeyUsage(user.APIKeyID, fc.Field.Name); err != nil {
+				log.Printf("Recording API key usage failed: %v", err)
+			}
+		}
+		return next(ctx)
+	}
+}
+
+// ValidateAuthRules makes sure every Query, Mutation and Subscription field
+// declares who may call it, so a new field cannot ship without one.
+func ValidateAuthRules(schema *ast.Schema) error {
+	var missing []string
+	for _, name := range rootTypes {
+		def := schema.Types[name]
+		if def == nil {
+			continue
+		}
+		for _, field := range def.Fields {
+			if len(field.Name) > 1 && field.Name[:2] == "__" {
+				continue
+			}
+			if field.Directives.ForName("auth") == nil {
+				missing = append(missing, name+"."+field.Name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("fields without @auth rule: %v", missing)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"revervation/backend/graph/model"
+	"revervation/backend/repository"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestSchemaHasAuthRules(t *testing.T) {
+	schema := NewExecutableSchema(Config{Resolvers: &Resolver{}}).Schema()
+	if err := ValidateAuthRules(schema); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateAuthRulesNamesMissingField(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Name: "test.graphqls", Input: `
+		enum Role { PUBLIC HOST }
+		directive @auth(requires: Role!) on FIELD_DEFINITION
+		type Query {
+			open: Boolean! @auth(requires: PUBLIC)
+			leaky: Boolean!
+		}
+		type Mutation {
+			change: Boolean!
+		}
+	`})
+
+	err := ValidateAuthRules(schema)
+	if err == nil {
+		t.Fatal("fields without @auth passed")
+	}
+	for _, field := range []string{"Query.leaky", "Mutation.change"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("error %q does not name %s", err, field)
+		}
+	}
+	if strings.Contains(err.Error(), "Query.open") {
+		t.Errorf("error %q names a field with @auth", err)
+	}
+}
+
+func TestAuthDirective(t *testing.T) {
+	guest := &repository.User{ReservationID: "r1"}
+	emailGuest := &repository.User{Email: "gast@example.com", SessionID: "s1", ReservationIDs: []string{"r2", "r3"}}
+	host := &repository.User{StaffID: "st1", Role: model.StaffRoleHost, IsAdmin: true}
+	manager := &repository.User{StaffID: "st2", Role: model.StaffRoleManager, IsAdmin: true}
+	apiKey := &repository.User{APIKeyID: "k1", Scopes: []string{"getAllReservation", "Reservation.history"}}
+
+	cases := []struct {
+		name     string
+		user     *repository.User
+		requires model.Role
+		object   string
+		field    string
+		args     map[string]any
+		obj      any
+		// wantErr is nil when the field resolves, otherwise whether the
+		// caller counts as authenticated.
+		wantErr *bool
+	}{
+		{name: "public without login", requires: model.RolePublic, object: "Query", field: "openingHours"},
+		{name: "guest field without login", requires: model.RoleGuest, object: "Query", field: "myReservations", wantErr: ptr(false)},
+		{name: "guest field as guest", user: guest, requires: model.RoleGuest, object: "Query", field: "myReservations"},
+		{name: "owner of the reservation", user: guest, requires: model.RoleReservationOwner, object: "Mutation", field: "cancelReservation", args: map[string]any{"id": "r1"}},
+		{name: "other reservation", user: guest, requires: model.RoleReservationOwner, object: "Mutation", field: "cancelReservation", args: map[string]any{"id": "r2"}, wantErr: ptr(true)},
+		{name: "owner by input", user: emailGuest, requires: model.RoleReservationOwner, object: "Mutation", field: "updateReservation", args: map[string]any{"input": model.UpdateReservation{ID: "r3"}}},
+		{name: "owner by parent object", user: emailGuest, requires: model.RoleReservationOwner, object: "Reservation", field: "history", obj: &model.Reservation{ID: "r2"}},
+		{name: "staff field as guest", user: guest, requires: model.RoleHost, object: "Query", field: "getAllReservation", wantErr: ptr(true)},
+		{name: "host for host field", user: host, requires: model.RoleHost, object: "Query", field: "getAllReservation"},
+		{name: "host for manager field", user: host, requires: model.RoleManager, object: "Query", field: "staffUsers", wantErr: ptr(true)},
+		{name: "manager for host field", user: manager, requires: model.RoleHost, object: "Query", field: "getAllReservation"},
+		{name: "staff for any reservation", user: host, requires: model.RoleReservationOwner, object: "Mutation", field: "cancelReservation", args: map[string]any{"id": "r9"}},
+		{name: "api key in scope", user: apiKey, requires: model.RoleHost, object: "Query", field: "getAllReservation"},
+		{name: "api key in nested scope", user: apiKey, requires: model.RoleReservationOwner, object: "Reservation", field: "history", obj: &model.Reservation{ID: "r1"}},
+		{name: "api key out of scope", user: apiKey, requires: model.RoleHost, object: "Query", field: "getReservation", wantErr: ptr(true)},
+		{name: "api key on public field", user: apiKey, requires: model.RolePublic, object: "Query", field: "openingHours", wantErr: ptr(true)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.user != nil {
+				ctx = repository.WithUser(ctx, tc.user)
+			}
+			ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+				Object: tc.object,
+				Field:  graphql.CollectedField{Field: &ast.Field{Name: tc.field}},
+				Args:   tc.args,
+			})
+
+			called := false
+			next := func(ctx context.Context) (any, error) {
+				called = true
+				return true, nil
+			}
+			_, err := Auth(ctx, tc.obj, next, tc.requires)
+
+			if tc.wantErr == nil {
+				if err != nil || !called {
+					t.Fatalf("got %v, want the field to resolve", err)
+				}
+				return
+			}
+			var authErr *AuthError
+			if !errors.As(err, &authErr) {
+				t.Fatalf("got %v, want an AuthError", err)
+			}
+			if called {
+				t.Error("resolver ran despite the error")
+			}
+			if authErr.Authenticated != *tc.wantErr || authErr.Required != tc.requires {
+				t.Errorf("got %+v, want authenticated %v for %s", authErr, *tc.wantErr, tc.requires)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
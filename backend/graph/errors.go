@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"revervation/backend/ratelimit"
+	"revervation/backend/repository"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrorPresenter attaches a machine readable code to the typed errors of the
+// repository layer so clients can react without parsing messages.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var slotFull *repository.SlotFullError
+	if errors.As(err, &slotFull) {
+		gqlErr.Extensions = map[string]any{
+			"code":                  "SLOT_FULL",
+			"slotStart":             slotFull.SlotStart,
+			"remainingCovers":       slotFull.RemainingCovers,
+			"remainingReservations": slotFull.RemainingReservations,
+		}
+	}
+
+	var invalidTransition *repository.InvalidTransitionError
+	if errors.As(err, &invalidTransition) {
+		gqlErr.Extensions = map[string]any{
+			"code": "INVALID_STATUS_TRANSITION",
+			"from": invalidTransition.From,
+			"to":   invalidTransition.To,
+		}
+	}
+
+	var forbiddenTransition *repository.TransitionForbiddenError
+	if errors.As(err, &forbiddenTransition) {
+		gqlErr.Extensions = map[string]any{
+			"code": "STATUS_TRANSITION_FORBIDDEN",
+			"from": forbiddenTransition.From,
+			"to":   forbiddenTransition.To,
+			"role": forbiddenTransition.Role,
+		}
+	}
+
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		code := "FORBIDDEN"
+		if !authErr.Authenticated {
+			code = "UNAUTHENTICATED"
+		}
+		gqlErr.Extensions = map[string]any{
+			"code":     code,
+			"requires": authErr.Required,
+		}
+	}
+
+	var limitErr *ratelimit.Error
+	if errors.As(err, &limitErr) {
+		gqlErr.Extensions = limitErr.Extensions()
+	}
+
+	return gqlErr
+}
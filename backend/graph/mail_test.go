@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"path/filepath"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"revervation/backend/mailer"
+	"revervation/backend/notifier"
+	"revervation/backend/ratelimit"
+	"revervation/backend/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+// mailTestServer serves the schema the way server.go does, with mails going
+// through the outbox into memory.
+type mailTestServer struct {
+	*httptest.Server
+	outbox    *repository.OutboxService
+	transport *mailer.MemoryTransport
+}
+
+func newMailTestServer(t *testing.T) *mailTestServer {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("FRONT_END_URI", "https://yoake.example")
+	if err := database.Init(database.DriverSQLite, filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	store, err := repository.NewReservationStore([]model.NotificationChannel{model.NotificationChannelEmail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	staff := repository.NewStaffStore()
+	auth := repository.NewAuthService(store, staff, repository.NewSessionStore(), repository.NewGuestLinkStore(), repository.NewTwoFactorStore(), repository.NewAPIKeyStore())
+	limiter := ratelimit.NewLimiter(ratelimit.Config{})
+
+	templates, err := mailer.NewTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	memory := mailer.NewMemoryTransport()
+	hours := repository.NewOpeningHoursStore()
+	mail := mailer.NewMailer(mailer.Config{From: "Yoake <reservierung@yoake.example>", VisitDuration: 2 * time.Hour}, memory, templates, hours)
+	outbox := repository.NewOutboxService(repository.OutboxConfig{MaxAttempts: 1, BackoffBase: time.Minute, BackoffMax: time.Minute, Lease: time.Minute}, store, notifier.NewNotifier(notifier.NewEmailChannel(mail)), mail)
+	reminders := repository.NewReminderService(repository.ReminderConfig{}, store, mail)
+
+	srv := handler.New(NewExecutableSchema(Config{
+		Resolvers:  NewResolver(store, auth, hours, staff, limiter, mail, outbox, reminders),
+		Directives: DirectiveRoot{Auth: Auth},
+	}))
+	srv.AddTransport(transport.POST{})
+	srv.SetErrorPresenter(ErrorPresenter)
+
+	server := httptest.NewServer(repository.Middleware(auth)(srv))
+	t.Cleanup(server.Close)
+	return &mailTestServer{Server: server, outbox: outbox, transport: memory}
+}
+
+// do runs a GraphQL operation, with token as the bearer token when set, and
+// decodes its data into out.
+func (s *mailTestServer) do(t *testing.T, token, query string, variables map[string]any, out any) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	raw, _ := io.ReadAll(res.Body)
+
+	var response struct {
+		Data   json.RawMessage
+		Errors []struct{ Message string }
+	}
+	if err := json.Unmarshal(raw, &response); err != nil {
+		t.Fatalf("%s: %v", raw, err)
+	}
+	if len(response.Errors) > 0 {
+		t.Fatalf("%s", raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(response.Data, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// sent delivers the outbox and returns recipient and subject of every mail
+// that went out, clearing the transport.
+func (s *mailTestServer) sent(t *testing.T) [][2]string {
+	t.Helper()
+	if _, err := s.outbox.DeliverDue(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var decoder mime.WordDecoder
+	var sent [][2]string
+	for _, msg := range s.transport.Messages() {
+		parsed, err := mail.ReadMessage(bytes.NewReader(msg.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		subject, err := decoder.DecodeHeader(parsed.Header.Get("Subject"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, [2]string{strings.Join(msg.To, ","), subject})
+	}
+	s.transport.Reset()
+	return sent
+}
+
+func TestMutationsSendMail(t *testing.T) {
+	server := newMailTestServer(t)
+	// The seeded opening hours serve dinner every day in Berlin.
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Now().In(berlin).AddDate(0, 0, 3)
+	reserveAt := time.Date(day.Year(), day.Month(), day.Day(), 19, 0, 0, 0, berlin)
+
+	var created struct {
+		CreateReservation struct {
+			Token       string
+			Reservation struct{ ID string }
+		}
+	}
+	server.do(t, "", `mutation($input: NewReservation!) {
+		createReservation(input: $input) { token reservation { id } }
+	}`, map[string]any{"input": map[string]any{
+		"lastName":    "Mustermann",
+		"amount":      2,
+		"phoneNumber": "+49 171 1234567",
+		"email":       "Erika@Example.com",
+		"reserveAt":   reserveAt.Format(time.RFC3339),
+	}}, &created)
+	assertSent(t, "createReservation", server.sent(t), [2]string{"Erika@Example.com", "Ihre Reservierung wurde erfasst"})
+
+	server.do(t, created.CreateReservation.Token, `mutation($id: ID!) {
+		cancelReservation(id: $id, reason: "Termin verschoben") { id }
+	}`, map[string]any{"id": created.CreateReservation.Reservation.ID}, nil)
+	assertSent(t, "cancelReservation", server.sent(t), [2]string{"Erika@Example.com", "Ihre Reservierung wurde storniert"})
+
+	// Canceled reservations are not upcoming, so book again for the link.
+	server.do(t, "", `mutation($input: NewReservation!) {
+		createReservation(input: $input) { token }
+	}`, map[string]any{"input": map[string]any{
+		"lastName":    "Mustermann",
+		"amount":      2,
+		"phoneNumber": "+49 171 1234567",
+		"email":       "erika@example.com",
+		"reserveAt":   reserveAt.Add(time.Hour).Format(time.RFC3339),
+	}}, nil)
+	server.sent(t)
+
+	for _, email := range []string{" ERIKA@example.com ", "unbekannt@example.com"} {
+		var requested struct{ RequestGuestLoginLink bool }
+		server.do(t, "", `mutation($email: String!) { requestGuestLoginLink(email: $email) }`, map[string]any{"email": email}, &requested)
+		if !requested.RequestGuestLoginLink {
+			t.Errorf("requestGuestLoginLink(%q) = false", email)
+		}
+	}
+	assertSent(t, "requestGuestLoginLink", server.sent(t), [2]string{"erika@example.com", "Ihr Anmeldelink für Ihre Reservierungen"})
+}
+
+func assertSent(t *testing.T, operation string, got [][2]string, want ...[2]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s sent %v, want %v", operation, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s sent %v, want %v", operation, got, want)
+		}
+	}
+}
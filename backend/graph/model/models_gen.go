@@ -10,38 +10,166 @@ import (
 	"time"
 )
 
+// A credential for another system. scopes are the operations it may call,
+// named like the root fields, or Type.field for nested fields.
+type APIKey struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Prefix     string         `json:"prefix"`
+	Scopes     []string       `json:"scopes"`
+	CreatedBy  *string        `json:"createdBy,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	LastUsedAt *time.Time     `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time     `json:"revokedAt,omitempty"`
+	Usage      []*APIKeyUsage `json:"usage"`
+}
+
+type APIKeyUsage struct {
+	Operation  string    `json:"operation"`
+	Count      int32     `json:"count"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+type AuthPayload struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+type AvailableSlot struct {
+	StartsAt              time.Time `json:"startsAt"`
+	EndsAt                time.Time `json:"endsAt"`
+	RemainingCovers       *int32    `json:"remainingCovers,omitempty"`
+	RemainingReservations *int32    `json:"remainingReservations,omitempty"`
+}
+
+type Closure struct {
+	ID            string    `json:"id"`
+	StartsOn      time.Time `json:"startsOn"`
+	EndsOn        time.Time `json:"endsOn"`
+	ServicePeriod *string   `json:"servicePeriod,omitempty"`
+	Reason        string    `json:"reason"`
+}
+
+// key is shown only once and cannot be read again.
+type CreatedAPIKey struct {
+	APIKey *APIKey `json:"apiKey"`
+	Key    string  `json:"key"`
+}
+
+// A rendered guest mail, see previewEmail.
+type EmailPreview struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+}
+
+type GuestLoginResponse struct {
+	Token        string         `json:"token"`
+	RefreshToken string         `json:"refreshToken"`
+	ExpiresAt    time.Time      `json:"expiresAt"`
+	Reservations []*Reservation `json:"reservations"`
+}
+
+type InviteStaff struct {
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"displayName"`
+	Role        StaffRole `json:"role"`
+}
+
+// Either auth is set and the login is complete, or challenge asks for a code.
+type LoginResult struct {
+	Auth      *AuthPayload   `json:"auth,omitempty"`
+	Challenge *TotpChallenge `json:"challenge,omitempty"`
+}
+
 type LoginWithReservationResponse struct {
-	Token       string       `json:"token"`
-	Reservation *Reservation `json:"reservation"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refreshToken"`
+	ExpiresAt    time.Time    `json:"expiresAt"`
+	Reservation  *Reservation `json:"reservation"`
 }
 
 type Mutation struct {
 }
 
+type NewAPIKey struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type NewClosure struct {
+	StartsOn      time.Time `json:"startsOn"`
+	EndsOn        time.Time `json:"endsOn"`
+	ServicePeriod *string   `json:"servicePeriod,omitempty"`
+	Reason        string    `json:"reason"`
+}
+
 type NewReservation struct {
-	FirstName   *string   `json:"firstName,omitempty"`
-	LastName    string    `json:"lastName"`
-	Amount      int32     `json:"amount"`
-	PhoneNumber string    `json:"phoneNumber"`
-	Email       string    `json:"email"`
-	ReserveAt   time.Time `json:"reserveAt"`
-	Notes       *string   `json:"notes,omitempty"`
+	FirstName            *string               `json:"firstName,omitempty"`
+	LastName             string                `json:"lastName"`
+	Amount               int32                 `json:"amount"`
+	PhoneNumber          string                `json:"phoneNumber"`
+	Email                string                `json:"email"`
+	ReserveAt            time.Time             `json:"reserveAt"`
+	Notes                *string               `json:"notes,omitempty"`
+	NotificationChannels []NotificationChannel `json:"notificationChannels,omitempty"`
+}
+
+type OpeningHours struct {
+	Timezone    string           `json:"timezone"`
+	SlotMinutes int32            `json:"slotMinutes"`
+	Periods     []*ServicePeriod `json:"periods"`
+}
+
+// A mail or SMS to a guest queued in the outbox. FAILED messages have used up
+// their delivery attempts and wait for resendEmail.
+type OutboxEmail struct {
+	ID            string                    `json:"id"`
+	ReservationID string                    `json:"reservationId"`
+	Event         ReservationEventBroadcast `json:"event"`
+	Channel       NotificationChannel       `json:"channel"`
+	Recipient     string                    `json:"recipient"`
+	Status        EmailStatus               `json:"status"`
+	Attempts      int32                     `json:"attempts"`
+	LastError     *string                   `json:"lastError,omitempty"`
+	NextAttemptAt time.Time                 `json:"nextAttemptAt"`
+	CreatedAt     time.Time                 `json:"createdAt"`
+	SentAt        *time.Time                `json:"sentAt,omitempty"`
 }
 
 type Query struct {
 }
 
 type Reservation struct {
-	ID          string            `json:"id"`
-	FirstName   *string           `json:"firstName,omitempty"`
-	LastName    string            `json:"lastName"`
-	PhoneNumber string            `json:"phoneNumber"`
-	Email       string            `json:"email"`
-	Amount      int32             `json:"amount"`
-	CreatedAt   time.Time         `json:"createdAt"`
-	ReserveAt   time.Time         `json:"reserveAt"`
-	Status      ReservationStatus `json:"status"`
-	Notes       *string           `json:"notes,omitempty"`
+	ID                   string                `json:"id"`
+	FirstName            *string               `json:"firstName,omitempty"`
+	LastName             string                `json:"lastName"`
+	PhoneNumber          string                `json:"phoneNumber"`
+	Email                string                `json:"email"`
+	Amount               int32                 `json:"amount"`
+	CreatedAt            time.Time             `json:"createdAt"`
+	ReserveAt            time.Time             `json:"reserveAt"`
+	Status               ReservationStatus     `json:"status"`
+	Notes                *string               `json:"notes,omitempty"`
+	History              []*ReservationEvent   `json:"history"`
+	NotificationChannels []NotificationChannel `json:"notificationChannels"`
+}
+
+type ReservationChange struct {
+	Field  string  `json:"field"`
+	Before *string `json:"before,omitempty"`
+	After  *string `json:"after,omitempty"`
+}
+
+type ReservationEvent struct {
+	ID        string               `json:"id"`
+	Type      ReservationEventType `json:"type"`
+	ActorRole string               `json:"actorRole"`
+	ActorID   string               `json:"actorId"`
+	Changes   []*ReservationChange `json:"changes"`
+	Reason    *string              `json:"reason,omitempty"`
+	CreatedAt time.Time            `json:"createdAt"`
 }
 
 type ReservationEventPayload struct {
@@ -68,20 +196,90 @@ type ReservationInfo struct {
 	TotalOpenReservation      int32                    `json:"totalOpenReservation"`
 	TotalConfirmedReservation int32                    `json:"totalConfirmedReservation"`
 	TotalCanceledReservation  int32                    `json:"totalCanceledReservation"`
+	TotalSeatedReservation    int32                    `json:"totalSeatedReservation"`
+	TotalCompletedReservation int32                    `json:"totalCompletedReservation"`
+	TotalNoShowReservation    int32                    `json:"totalNoShowReservation"`
+	NoShowRate                float64                  `json:"noShowRate"`
+	SeatedCovers              int32                    `json:"seatedCovers"`
 	ByHours                   []*ReservationInfoByHour `json:"byHours"`
+	Closures                  []*Closure               `json:"closures"`
 }
 
 type ReservationInfoByHour struct {
 	TotalReservation    int32     `json:"totalReservation"`
 	TotalPerson         int32     `json:"totalPerson"`
 	TotalBigReservation int32     `json:"totalBigReservation"`
+	TotalSeatedPerson   int32     `json:"totalSeatedPerson"`
 	StartsAt            time.Time `json:"startsAt"`
 	EndsAt              time.Time `json:"endsAt"`
 }
 
+type ServicePeriod struct {
+	ID       string  `json:"id"`
+	Weekday  Weekday `json:"weekday"`
+	Name     string  `json:"name"`
+	OpensAt  string  `json:"opensAt"`
+	ClosesAt string  `json:"closesAt"`
+}
+
+type ServicePeriodInput struct {
+	Name     string `json:"name"`
+	OpensAt  string `json:"opensAt"`
+	ClosesAt string `json:"closesAt"`
+}
+
+type Session struct {
+	ID         string    `json:"id"`
+	TokenType  string    `json:"tokenType"`
+	Subject    string    `json:"subject"`
+	UserAgent  *string   `json:"userAgent,omitempty"`
+	IPAddress  *string   `json:"ipAddress,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+type StaffInvite struct {
+	Staff       *StaffUser `json:"staff"`
+	InviteToken string     `json:"inviteToken"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+}
+
+type StaffUser struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	DisplayName   string    `json:"displayName"`
+	Role          StaffRole `json:"role"`
+	Disabled      bool      `json:"disabled"`
+	PendingInvite bool      `json:"pendingInvite"`
+	TotpEnabled   bool      `json:"totpEnabled"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
 type Subscription struct {
 }
 
+// Second step of a staff login. The token is passed to verifyTotpLogin with a
+// code. Enrollment is set when two-factor authentication is required but the
+// account has not set it up yet; the code then confirms the new authenticator.
+type TotpChallenge struct {
+	Token      string          `json:"token"`
+	ExpiresAt  time.Time       `json:"expiresAt"`
+	Enrollment *TotpEnrollment `json:"enrollment,omitempty"`
+}
+
+type TotpEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+type TotpLoginResult struct {
+	Auth          *AuthPayload `json:"auth"`
+	RecoveryCodes []string     `json:"recoveryCodes"`
+}
+
 type UpdateReservation struct {
 	ID          string     `json:"id"`
 	FirstName   *string    `json:"firstName,omitempty"`
@@ -93,14 +291,132 @@ type UpdateReservation struct {
 	Notes       *string    `json:"notes,omitempty"`
 }
 
+type EmailStatus string
+
+const (
+	EmailStatusPending EmailStatus = "PENDING"
+	EmailStatusSent    EmailStatus = "SENT"
+	EmailStatusFailed  EmailStatus = "FAILED"
+)
+
+var AllEmailStatus = []EmailStatus{
+	EmailStatusPending,
+	EmailStatusSent,
+	EmailStatusFailed,
+}
+
+func (e EmailStatus) IsValid() bool {
+	switch e {
+	case EmailStatusPending, EmailStatusSent, EmailStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e EmailStatus) String() string {
+	return string(e)
+}
+
+func (e *EmailStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = EmailStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid EmailStatus", str)
+	}
+	return nil
+}
+
+func (e EmailStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *EmailStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e EmailStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type NotificationChannel string
+
+const (
+	NotificationChannelEmail NotificationChannel = "EMAIL"
+	NotificationChannelSms   NotificationChannel = "SMS"
+)
+
+var AllNotificationChannel = []NotificationChannel{
+	NotificationChannelEmail,
+	NotificationChannelSms,
+}
+
+func (e NotificationChannel) IsValid() bool {
+	switch e {
+	case NotificationChannelEmail, NotificationChannelSms:
+		return true
+	}
+	return false
+}
+
+func (e NotificationChannel) String() string {
+	return string(e)
+}
+
+func (e *NotificationChannel) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationChannel(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationChannel", str)
+	}
+	return nil
+}
+
+func (e NotificationChannel) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *NotificationChannel) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e NotificationChannel) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+// What happened to a reservation. GUEST_LOGIN is never broadcast; it marks the
+// login link mails in the outbox.
 type ReservationEventBroadcast string
 
 const (
-	ReservationEventBroadcastCreated   ReservationEventBroadcast = "CREATED"
-	ReservationEventBroadcastUpdated   ReservationEventBroadcast = "UPDATED"
-	ReservationEventBroadcastCanceled  ReservationEventBroadcast = "CANCELED"
-	ReservationEventBroadcastConfirmed ReservationEventBroadcast = "CONFIRMED"
-	ReservationEventBroadcastDeclined  ReservationEventBroadcast = "DECLINED"
+	ReservationEventBroadcastCreated    ReservationEventBroadcast = "CREATED"
+	ReservationEventBroadcastUpdated    ReservationEventBroadcast = "UPDATED"
+	ReservationEventBroadcastCanceled   ReservationEventBroadcast = "CANCELED"
+	ReservationEventBroadcastConfirmed  ReservationEventBroadcast = "CONFIRMED"
+	ReservationEventBroadcastDeclined   ReservationEventBroadcast = "DECLINED"
+	ReservationEventBroadcastSeated     ReservationEventBroadcast = "SEATED"
+	ReservationEventBroadcastCompleted  ReservationEventBroadcast = "COMPLETED"
+	ReservationEventBroadcastNoShow     ReservationEventBroadcast = "NO_SHOW"
+	ReservationEventBroadcastGuestLogin ReservationEventBroadcast = "GUEST_LOGIN"
 )
 
 var AllReservationEventBroadcast = []ReservationEventBroadcast{
@@ -109,11 +425,15 @@ var AllReservationEventBroadcast = []ReservationEventBroadcast{
 	ReservationEventBroadcastCanceled,
 	ReservationEventBroadcastConfirmed,
 	ReservationEventBroadcastDeclined,
+	ReservationEventBroadcastSeated,
+	ReservationEventBroadcastCompleted,
+	ReservationEventBroadcastNoShow,
+	ReservationEventBroadcastGuestLogin,
 }
 
 func (e ReservationEventBroadcast) IsValid() bool {
 	switch e {
-	case ReservationEventBroadcastCreated, ReservationEventBroadcastUpdated, ReservationEventBroadcastCanceled, ReservationEventBroadcastConfirmed, ReservationEventBroadcastDeclined:
+	case ReservationEventBroadcastCreated, ReservationEventBroadcastUpdated, ReservationEventBroadcastCanceled, ReservationEventBroadcastConfirmed, ReservationEventBroadcastDeclined, ReservationEventBroadcastSeated, ReservationEventBroadcastCompleted, ReservationEventBroadcastNoShow, ReservationEventBroadcastGuestLogin:
 		return true
 	}
 	return false
@@ -154,6 +474,65 @@ func (e ReservationEventBroadcast) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+type ReservationEventType string
+
+const (
+	ReservationEventTypeCreated             ReservationEventType = "CREATED"
+	ReservationEventTypeUpdated             ReservationEventType = "UPDATED"
+	ReservationEventTypeStatusChanged       ReservationEventType = "STATUS_CHANGED"
+	ReservationEventTypeAttendanceConfirmed ReservationEventType = "ATTENDANCE_CONFIRMED"
+)
+
+var AllReservationEventType = []ReservationEventType{
+	ReservationEventTypeCreated,
+	ReservationEventTypeUpdated,
+	ReservationEventTypeStatusChanged,
+	ReservationEventTypeAttendanceConfirmed,
+}
+
+func (e ReservationEventType) IsValid() bool {
+	switch e {
+	case ReservationEventTypeCreated, ReservationEventTypeUpdated, ReservationEventTypeStatusChanged, ReservationEventTypeAttendanceConfirmed:
+		return true
+	}
+	return false
+}
+
+func (e ReservationEventType) String() string {
+	return string(e)
+}
+
+func (e *ReservationEventType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReservationEventType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReservationEventType", str)
+	}
+	return nil
+}
+
+func (e ReservationEventType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ReservationEventType) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ReservationEventType) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type ReservationStatus string
 
 const (
@@ -161,6 +540,9 @@ const (
 	ReservationStatusConfirmed ReservationStatus = "CONFIRMED"
 	ReservationStatusCanceled  ReservationStatus = "CANCELED"
 	ReservationStatusDeclined  ReservationStatus = "DECLINED"
+	ReservationStatusSeated    ReservationStatus = "SEATED"
+	ReservationStatusCompleted ReservationStatus = "COMPLETED"
+	ReservationStatusNoShow    ReservationStatus = "NO_SHOW"
 )
 
 var AllReservationStatus = []ReservationStatus{
@@ -168,11 +550,14 @@ var AllReservationStatus = []ReservationStatus{
 	ReservationStatusConfirmed,
 	ReservationStatusCanceled,
 	ReservationStatusDeclined,
+	ReservationStatusSeated,
+	ReservationStatusCompleted,
+	ReservationStatusNoShow,
 }
 
 func (e ReservationStatus) IsValid() bool {
 	switch e {
-	case ReservationStatusOpen, ReservationStatusConfirmed, ReservationStatusCanceled, ReservationStatusDeclined:
+	case ReservationStatusOpen, ReservationStatusConfirmed, ReservationStatusCanceled, ReservationStatusDeclined, ReservationStatusSeated, ReservationStatusCompleted, ReservationStatusNoShow:
 		return true
 	}
 	return false
@@ -212,3 +597,193 @@ func (e ReservationStatus) MarshalJSON() ([]byte, error) {
 	e.MarshalGQL(&buf)
 	return buf.Bytes(), nil
 }
+
+// Who may resolve a field. PUBLIC needs no login, GUEST admits anyone who is
+// signed in, RESERVATION_OWNER admits any staff member and the guest holding the
+// token of the reservation the field is about, or of its email address. The
+// staff roles are ranked OWNER > MANAGER > HOST; a higher role satisfies a lower
+// requirement.
+type Role string
+
+const (
+	RolePublic           Role = "PUBLIC"
+	RoleGuest            Role = "GUEST"
+	RoleReservationOwner Role = "RESERVATION_OWNER"
+	RoleHost             Role = "HOST"
+	RoleManager          Role = "MANAGER"
+	RoleOwner            Role = "OWNER"
+)
+
+var AllRole = []Role{
+	RolePublic,
+	RoleGuest,
+	RoleReservationOwner,
+	RoleHost,
+	RoleManager,
+	RoleOwner,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RolePublic, RoleGuest, RoleReservationOwner, RoleHost, RoleManager, RoleOwner:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Role) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Role) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type StaffRole string
+
+const (
+	StaffRoleOwner   StaffRole = "OWNER"
+	StaffRoleManager StaffRole = "MANAGER"
+	StaffRoleHost    StaffRole = "HOST"
+)
+
+var AllStaffRole = []StaffRole{
+	StaffRoleOwner,
+	StaffRoleManager,
+	StaffRoleHost,
+}
+
+func (e StaffRole) IsValid() bool {
+	switch e {
+	case StaffRoleOwner, StaffRoleManager, StaffRoleHost:
+		return true
+	}
+	return false
+}
+
+func (e StaffRole) String() string {
+	return string(e)
+}
+
+func (e *StaffRole) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = StaffRole(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid StaffRole", str)
+	}
+	return nil
+}
+
+func (e StaffRole) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *StaffRole) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e StaffRole) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type Weekday string
+
+const (
+	WeekdayMonday    Weekday = "MONDAY"
+	WeekdayTuesday   Weekday = "TUESDAY"
+	WeekdayWednesday Weekday = "WEDNESDAY"
+	WeekdayThursday  Weekday = "THURSDAY"
+	WeekdayFriday    Weekday = "FRIDAY"
+	WeekdaySaturday  Weekday = "SATURDAY"
+	WeekdaySunday    Weekday = "SUNDAY"
+)
+
+var AllWeekday = []Weekday{
+	WeekdayMonday,
+	WeekdayTuesday,
+	WeekdayWednesday,
+	WeekdayThursday,
+	WeekdayFriday,
+	WeekdaySaturday,
+	WeekdaySunday,
+}
+
+func (e Weekday) IsValid() bool {
+	switch e {
+	case WeekdayMonday, WeekdayTuesday, WeekdayWednesday, WeekdayThursday, WeekdayFriday, WeekdaySaturday, WeekdaySunday:
+		return true
+	}
+	return false
+}
+
+func (e Weekday) String() string {
+	return string(e)
+}
+
+func (e *Weekday) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Weekday(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Weekday", str)
+	}
+	return nil
+}
+
+func (e Weekday) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Weekday) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Weekday) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
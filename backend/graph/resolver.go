@@ -1,11 +1,14 @@
 package graph
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"revervation/backend/graph/model"
 	"revervation/backend/mailer"
-	"strconv"
+	"revervation/backend/ratelimit"
+	"revervation/backend/repository"
+	"slices"
+	"strings"
 	"sync"
 )
 
@@ -13,28 +16,31 @@ type Resolver struct {
 	mu          sync.RWMutex
 	subscribers map[string]chan *model.ReservationEventPayload
 	mailer      *mailer.Mailer
+	store       repository.ReservationStore
+	auth        *repository.AuthService
+	hours       *repository.OpeningHoursRepository
+	staff       *repository.StaffRepository
+	limiter     *ratelimit.Limiter
+	outbox      *repository.OutboxService
+	reminders   *repository.ReminderService
 }
 
-func NewResolver() *Resolver {
-	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
-	if err != nil {
-		panic(err)
-	}
-
-	cfg := mailer.Config{
-		Host:     os.Getenv("SMTP_HOST"),
-		Port:     port,
-		Username: os.Getenv("SMTP_USERNAME"),
-		Password: os.Getenv("SMTP_PASSWORD"),
-		From:     os.Getenv("SMTP_FROM"),
-	}
-
+func NewResolver(store repository.ReservationStore, auth *repository.AuthService, hours *repository.OpeningHoursRepository, staff *repository.StaffRepository, limiter *ratelimit.Limiter, mail *mailer.Mailer, outbox *repository.OutboxService, reminders *repository.ReminderService) *Resolver {
 	return &Resolver{
 		subscribers: make(map[string]chan *model.ReservationEventPayload),
-		mailer:      mailer.NewMailer(cfg),
+		mailer:      mail,
+		outbox:      outbox,
+		reminders:   reminders,
+		store:       store,
+		auth:        auth,
+		hours:       hours,
+		staff:       staff,
+		limiter:     limiter,
 	}
 }
 
+// broadcastUpdate pushes a change to the dashboard subscribers. Guest mails
+// are queued by the repository in the same transaction as the change.
 func (r *Resolver) broadcastUpdate(reservation *model.Reservation, event model.ReservationEventBroadcast) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -42,13 +48,6 @@ func (r *Resolver) broadcastUpdate(reservation *model.Reservation, event model.R
 		Reservation: reservation,
 		Event:       event,
 	}
-	go func() {
-		fmt.Println("Sending Email")
-		err := r.mailer.SendReservationStatusEmail(reservation, event)
-		if err != nil {
-			fmt.Println(err)
-		}
-	}()
 	for _, ch := range r.subscribers {
 		select {
 		case ch <- payload:
@@ -73,3 +72,43 @@ func (r *Resolver) unsubscribe(id string) {
 		delete(r.subscribers, id)
 	}
 }
+
+// limited runs a login-like attempt under the limits of operation op for the
+// caller's IP and the targeted account. A failed attempt counts towards the
+// lockout, a successful one clears it.
+func limited[T any](ctx context.Context, limiter *ratelimit.Limiter, op, account string, attempt func() (T, error)) (T, error) {
+	ip := repository.ClientForContext(ctx).IPAddress
+	if err := limiter.Allow(op, ip, account); err != nil {
+		var zero T
+		return zero, err
+	}
+	result, err := attempt()
+	if err != nil {
+		limiter.Failed(op, ip, account)
+	} else {
+		limiter.Succeeded(op, ip, account)
+	}
+	return result, err
+}
+
+// apiKeyScopes is computed once; the schema does not change at runtime.
+var apiKeyScopes = sync.OnceValue(func() []string {
+	return APIKeyScopes(parsedSchema)
+})
+
+// checkAPIKeyScopes rejects operations a key cannot be granted and drops
+// duplicates.
+func checkAPIKeyScopes(requested []string) ([]string, error) {
+	allowed := apiKeyScopes()
+	var scopes []string
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(allowed, scope) {
+			return nil, fmt.Errorf("Die Operation %q kann einem API-Schlüssel nicht erlaubt werden.", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
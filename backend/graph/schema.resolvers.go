@@ -7,8 +7,10 @@ package graph
 import (
 	"context"
 	"fmt"
+	"log"
 	"revervation/backend/graph/model"
 	"revervation/backend/repository"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,7 +18,6 @@ import (
 
 // CreateReservation is the resolver for the createReservation field.
 func (r *mutationResolver) CreateReservation(ctx context.Context, input model.NewReservation) (*model.LoginWithReservationResponse, error) {
-	repo := repository.NewReservationRepository()
 	emptyString := " "
 	fmt.Println(input.FirstName)
 	if input.FirstName == nil {
@@ -35,33 +36,27 @@ func (r *mutationResolver) CreateReservation(ctx context.Context, input model.Ne
 		Status:      model.ReservationStatusOpen,
 		Notes:       input.Notes,
 	}
-	if err := repo.Create(reservation); err != nil {
+	actor := repository.Actor{Role: repository.ActorGuest, ID: reservation.ID}
+	if user := repository.ForContext(ctx); user != nil && user.IsAdmin {
+		actor = user.Actor()
+	}
+	if err := r.store.Create(actor, reservation, input.NotificationChannels); err != nil {
 		return nil, err
 	}
 
-	authService := repository.NewAuthService()
-
-	token, err := authService.SignToken(reservation.ID)
+	response, err := r.auth.GuestLogin(reservation, repository.ClientForContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 
 	r.Resolver.broadcastUpdate(reservation, model.ReservationEventBroadcastCreated)
-	response := model.LoginWithReservationResponse{Token: token, Reservation: reservation}
-	return &response, nil
+	return response, nil
 }
 
 // UpdateReservation is the resolver for the updateReservation field.
 func (r *mutationResolver) UpdateReservation(ctx context.Context, input model.UpdateReservation) (*model.Reservation, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	if user.ReservationID != input.ID && !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	repo := repository.NewReservationRepository()
-	reservation, err := repo.Update(input.ID, input.FirstName, input.LastName, input.Amount, input.ReserveAt, input.Notes, input.PhoneNumber, input.Email)
+	reservation, err := r.store.Update(user.Actor(), input.ID, input.FirstName, input.LastName, input.Amount, input.ReserveAt, input.Notes, input.PhoneNumber, input.Email)
 	if err != nil {
 		return nil, err
 	}
@@ -70,16 +65,9 @@ func (r *mutationResolver) UpdateReservation(ctx context.Context, input model.Up
 }
 
 // CancelReservation is the resolver for the cancelReservation field.
-func (r *mutationResolver) CancelReservation(ctx context.Context, id string) (*model.Reservation, error) {
+func (r *mutationResolver) CancelReservation(ctx context.Context, id string, reason *string) (*model.Reservation, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	if user.ReservationID != id && !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	repo := repository.NewReservationRepository()
-	reservation, err := repo.UpdateStatus(id, model.ReservationStatusCanceled)
+	reservation, err := r.store.UpdateStatus(user.Actor(), id, model.ReservationStatusCanceled, reason)
 	if err != nil {
 		return nil, err
 	}
@@ -87,17 +75,15 @@ func (r *mutationResolver) CancelReservation(ctx context.Context, id string) (*m
 	return reservation, nil
 }
 
+// SetNotificationChannels is the resolver for the setNotificationChannels field.
+func (r *mutationResolver) SetNotificationChannels(ctx context.Context, id string, channels []model.NotificationChannel) (*model.Reservation, error) {
+	return r.store.SetNotificationChannels(id, channels)
+}
+
 // OpenReservation is the resolver for the openReservation field.
-func (r *mutationResolver) OpenReservation(ctx context.Context, id string) (*model.Reservation, error) {
+func (r *mutationResolver) OpenReservation(ctx context.Context, id string, reason *string) (*model.Reservation, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	repo := repository.NewReservationRepository()
-	reservation, err := repo.UpdateStatus(id, model.ReservationStatusOpen)
+	reservation, err := r.store.UpdateStatus(user.Actor(), id, model.ReservationStatusOpen, reason)
 	if err != nil {
 		return nil, err
 	}
@@ -106,16 +92,9 @@ func (r *mutationResolver) OpenReservation(ctx context.Context, id string) (*mod
 }
 
 // ConfirmReservation is the resolver for the confirmReservation field.
-func (r *mutationResolver) ConfirmReservation(ctx context.Context, id string) (*model.Reservation, error) {
+func (r *mutationResolver) ConfirmReservation(ctx context.Context, id string, reason *string) (*model.Reservation, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	repo := repository.NewReservationRepository()
-	reservation, err := repo.UpdateStatus(id, model.ReservationStatusConfirmed)
+	reservation, err := r.store.UpdateStatus(user.Actor(), id, model.ReservationStatusConfirmed, reason)
 	if err != nil {
 		return nil, err
 	}
@@ -124,169 +103,378 @@ func (r *mutationResolver) ConfirmReservation(ctx context.Context, id string) (*
 }
 
 // DeclineReservation is the resolver for the declineReservation field.
-func (r *mutationResolver) DeclineReservation(ctx context.Context, id string) (*model.Reservation, error) {
+func (r *mutationResolver) DeclineReservation(ctx context.Context, id string, reason *string) (*model.Reservation, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
+	reservation, err := r.store.UpdateStatus(user.Actor(), id, model.ReservationStatusDeclined, reason)
+	if err != nil {
+		return nil, err
 	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
+	r.Resolver.broadcastUpdate(reservation, model.ReservationEventBroadcastDeclined)
+	return reservation, nil
+}
+
+// SeatReservation is the resolver for the seatReservation field.
+func (r *mutationResolver) SeatReservation(ctx context.Context, id string, reason *string) (*model.Reservation, error) {
+	user := repository.ForContext(ctx)
+	reservation, err := r.store.UpdateStatus(user.Actor(), id, model.ReservationStatusSeated, reason)
+	if err != nil {
+		return nil, err
 	}
-	repo := repository.NewReservationRepository()
-	reservation, err := repo.UpdateStatus(id, model.ReservationStatusDeclined)
+	r.Resolver.broadcastUpdate(reservation, model.ReservationEventBroadcastSeated)
+	return reservation, nil
+}
+
+// CompleteReservation is the resolver for the completeReservation field.
+func (r *mutationResolver) CompleteReservation(ctx context.Context, id string, reason *string) (*model.Reservation, error) {
+	user := repository.ForContext(ctx)
+	reservation, err := r.store.UpdateStatus(user.Actor(), id, model.ReservationStatusCompleted, reason)
 	if err != nil {
 		return nil, err
 	}
-	r.Resolver.broadcastUpdate(reservation, model.ReservationEventBroadcastDeclined)
+	r.Resolver.broadcastUpdate(reservation, model.ReservationEventBroadcastCompleted)
+	return reservation, nil
+}
+
+// MarkReservationNoShow is the resolver for the markReservationNoShow field.
+func (r *mutationResolver) MarkReservationNoShow(ctx context.Context, id string, reason *string) (*model.Reservation, error) {
+	user := repository.ForContext(ctx)
+	reservation, err := r.store.UpdateStatus(user.Actor(), id, model.ReservationStatusNoShow, reason)
+	if err != nil {
+		return nil, err
+	}
+	r.Resolver.broadcastUpdate(reservation, model.ReservationEventBroadcastNoShow)
 	return reservation, nil
 }
 
 // Login is the resolver for the login field.
-func (r *mutationResolver) Login(ctx context.Context, username string, password string) (string, error) {
-	auth := repository.NewAuthService()
-	return auth.Login(username, password)
+func (r *mutationResolver) Login(ctx context.Context, username string, password string) (*model.LoginResult, error) {
+	account := strings.ToLower(strings.TrimSpace(username))
+	return limited(ctx, r.limiter, "login", account, func() (*model.LoginResult, error) {
+		return r.auth.Login(username, password, repository.ClientForContext(ctx))
+	})
+}
+
+// VerifyTotpLogin is the resolver for the verifyTotpLogin field.
+func (r *mutationResolver) VerifyTotpLogin(ctx context.Context, challenge string, code string) (*model.TotpLoginResult, error) {
+	// Failures count against the challenge, so each password step allows
+	// only a few guesses at the code.
+	return limited(ctx, r.limiter, "verifyTotpLogin", challenge, func() (*model.TotpLoginResult, error) {
+		return r.auth.VerifyTOTPLogin(challenge, code, repository.ClientForContext(ctx))
+	})
 }
 
 // LoginWithReservation is the resolver for the loginWithReservation field.
 func (r *mutationResolver) LoginWithReservation(ctx context.Context, id string, lastName string) (*model.LoginWithReservationResponse, error) {
-	auth := repository.NewAuthService()
-	return auth.LoginWithReservation(id, lastName)
+	return limited(ctx, r.limiter, "loginWithReservation", id, func() (*model.LoginWithReservationResponse, error) {
+		return r.auth.LoginWithReservation(id, lastName, repository.ClientForContext(ctx))
+	})
+}
+
+// RequestGuestLoginLink is the resolver for the requestGuestLoginLink field.
+func (r *mutationResolver) RequestGuestLoginLink(ctx context.Context, email string) (bool, error) {
+	account := strings.ToLower(strings.TrimSpace(email))
+	if err := r.limiter.Allow("requestGuestLoginLink", repository.ClientForContext(ctx).IPAddress, account); err != nil {
+		return false, err
+	}
+	// The answer is the same whether or not the address has reservations, so
+	// the mutation cannot be used to probe for guests.
+	if err := r.auth.RequestGuestLoginLink(email); err != nil {
+		log.Printf("Queueing guest login link for %s failed: %v", account, err)
+	}
+	return true, nil
+}
+
+// RedeemGuestLoginLink is the resolver for the redeemGuestLoginLink field.
+func (r *mutationResolver) RedeemGuestLoginLink(ctx context.Context, token string) (*model.GuestLoginResponse, error) {
+	return limited(ctx, r.limiter, "redeemGuestLoginLink", "", func() (*model.GuestLoginResponse, error) {
+		return r.auth.RedeemGuestLoginLink(token, repository.ClientForContext(ctx))
+	})
+}
+
+// RespondToReminder is the resolver for the respondToReminder field.
+func (r *mutationResolver) RespondToReminder(ctx context.Context, token string, attending bool) (*model.Reservation, error) {
+	return limited(ctx, r.limiter, "respondToReminder", "", func() (*model.Reservation, error) {
+		reservation, err := r.reminders.Respond(token, attending)
+		if err != nil {
+			return nil, err
+		}
+		if !attending {
+			r.Resolver.broadcastUpdate(reservation, model.ReservationEventBroadcastCanceled)
+		}
+		return reservation, nil
+	})
 }
 
 // SendMessageToReservation is the resolver for the sendMessageToReservation field.
 func (r *mutationResolver) SendMessageToReservation(ctx context.Context, id string, content string) (bool, error) {
-	repo := repository.NewReservationRepository()
-	err := repo.SendMessageToReservation(id, content, r.mailer)
+	user := repository.ForContext(ctx)
+	existing, err := r.store.GetByID(id)
 	if err != nil {
 		return false, err
 	}
+	if existing == nil || existing.Email == "" {
+		return false, fmt.Errorf("reservation not found or missing email")
+	}
 
-	_, err = repo.UpdateStatus(id, model.ReservationStatusDeclined)
+	reason := "Abgelehnt mit persönlicher Nachricht"
+	reservation, err := r.store.DeclineWithMessage(user.Actor(), id, &reason, content)
 	if err != nil {
 		return false, err
 	}
+	r.Resolver.broadcastUpdate(reservation, model.ReservationEventBroadcastDeclined)
+	return true, nil
+}
 
-	return true, err
+// UpdateOpeningHoursSettings is the resolver for the updateOpeningHoursSettings field.
+func (r *mutationResolver) UpdateOpeningHoursSettings(ctx context.Context, timezone string, slotMinutes int32) (*model.OpeningHours, error) {
+	return r.hours.UpdateSettings(timezone, slotMinutes)
 }
 
-// GetReservation is the resolver for the getReservation field.
-func (r *queryResolver) GetReservation(ctx context.Context, filter model.ReservationFilter) ([]*model.Reservation, error) {
+// SetServicePeriods is the resolver for the setServicePeriods field.
+func (r *mutationResolver) SetServicePeriods(ctx context.Context, weekday model.Weekday, periods []*model.ServicePeriodInput) (*model.OpeningHours, error) {
+	return r.hours.SetServicePeriods(weekday, periods)
+}
+
+// CreateClosure is the resolver for the createClosure field.
+func (r *mutationResolver) CreateClosure(ctx context.Context, input model.NewClosure) (*model.Closure, error) {
+	return r.hours.CreateClosure(input)
+}
+
+// DeleteClosure is the resolver for the deleteClosure field.
+func (r *mutationResolver) DeleteClosure(ctx context.Context, id string) (bool, error) {
+	return r.hours.DeleteClosure(id)
+}
+
+// InviteStaff is the resolver for the inviteStaff field.
+func (r *mutationResolver) InviteStaff(ctx context.Context, input model.InviteStaff) (*model.StaffInvite, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
+	staff, token, err := r.staff.Invite(user, input)
+	if err != nil {
+		return nil, err
 	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
+	return &model.StaffInvite{Staff: staff.ToModel(), InviteToken: token, ExpiresAt: *staff.InviteExpiresAt}, nil
+}
+
+// AcceptStaffInvite is the resolver for the acceptStaffInvite field.
+func (r *mutationResolver) AcceptStaffInvite(ctx context.Context, token string, password string) (*model.LoginResult, error) {
+	return limited(ctx, r.limiter, "acceptStaffInvite", "", func() (*model.LoginResult, error) {
+		return r.auth.AcceptStaffInvite(token, password, repository.ClientForContext(ctx))
+	})
+}
+
+// ResetStaffPassword is the resolver for the resetStaffPassword field.
+func (r *mutationResolver) ResetStaffPassword(ctx context.Context, id string) (*model.StaffUser, error) {
+	user := repository.ForContext(ctx)
+	staff, token, err := r.staff.ResetPassword(user, id)
+	if err != nil {
+		return nil, err
 	}
-	repo := repository.NewReservationRepository()
-	return repo.GetByFilter(filter)
+	if err := r.auth.RevokeStaffSessions(staff.ID); err != nil {
+		return nil, err
+	}
+	// Only the account's owner gets the token, so whoever resets a password
+	// cannot set it themselves.
+	if err := r.mailer.SendStaffPasswordReset(staff.Email, staff.DisplayName, token); err != nil {
+		log.Printf("Mailing the password reset of %s failed: %v", staff.Username, err)
+		return nil, fmt.Errorf("Die E-Mail zum Zurücksetzen konnte nicht gesendet werden. Bitte versuchen Sie es erneut.")
+	}
+	return staff.ToModel(), nil
 }
 
-// GetAllReservation is the resolver for the getAllReservation field.
-func (r *queryResolver) GetAllReservation(ctx context.Context) ([]*model.Reservation, error) {
+// DisableStaff is the resolver for the disableStaff field.
+func (r *mutationResolver) DisableStaff(ctx context.Context, id string) (*model.StaffUser, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
+	staff, err := r.staff.SetDisabled(user, id, true)
+	if err != nil {
+		return nil, err
 	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
+	if err := r.auth.RevokeStaffSessions(staff.ID); err != nil {
+		return nil, err
 	}
-	repo := repository.NewReservationRepository()
-	return repo.GetAll()
+	return staff.ToModel(), nil
 }
 
-// GetReservationInfo is the resolver for the getReservationInfo field.
-func (r *queryResolver) GetReservationInfo(ctx context.Context, date *time.Time) (*model.ReservationInfo, error) {
+// EnableStaff is the resolver for the enableStaff field.
+func (r *mutationResolver) EnableStaff(ctx context.Context, id string) (*model.StaffUser, error) {
+	user := repository.ForContext(ctx)
+	staff, err := r.staff.SetDisabled(user, id, false)
+	if err != nil {
+		return nil, err
+	}
+	return staff.ToModel(), nil
+}
+
+// ChangeStaffRole is the resolver for the changeStaffRole field.
+func (r *mutationResolver) ChangeStaffRole(ctx context.Context, id string, role model.StaffRole) (*model.StaffUser, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
+	staff, err := r.staff.ChangeRole(user, id, role)
+	if err != nil {
+		return nil, err
 	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
+	return staff.ToModel(), nil
+}
+
+// RefreshToken is the resolver for the refreshToken field.
+func (r *mutationResolver) RefreshToken(ctx context.Context, token string) (*model.AuthPayload, error) {
+	return limited(ctx, r.limiter, "refreshToken", "", func() (*model.AuthPayload, error) {
+		return r.auth.Refresh(token, repository.ClientForContext(ctx))
+	})
+}
+
+// RevokeSession is the resolver for the revokeSession field.
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	user := repository.ForContext(ctx)
+	if err := r.auth.RevokeSession(user, id); err != nil {
+		return false, err
 	}
-	repo := repository.NewReservationRepository()
-	return repo.GetStats(date)
+	return true, nil
 }
 
-// GetReservationToday returns all reservations for today
-func (r *queryResolver) GetReservationToday(ctx context.Context) ([]*model.Reservation, error) {
+// BeginTotpEnrollment is the resolver for the beginTotpEnrollment field.
+func (r *mutationResolver) BeginTotpEnrollment(ctx context.Context) (*model.TotpEnrollment, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
+	staff, err := r.staff.GetByID(user.StaffID)
+	if err != nil {
+		return nil, err
 	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
+	return r.auth.BeginTOTPEnrollment(staff)
+}
+
+// ConfirmTotpEnrollment is the resolver for the confirmTotpEnrollment field.
+func (r *mutationResolver) ConfirmTotpEnrollment(ctx context.Context, code string) ([]string, error) {
+	return r.auth.ConfirmTOTPEnrollment(repository.ForContext(ctx), code)
+}
+
+// RegenerateRecoveryCodes is the resolver for the regenerateRecoveryCodes field.
+func (r *mutationResolver) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	return r.auth.RegenerateRecoveryCodes(repository.ForContext(ctx), code)
+}
+
+// DisableTotp is the resolver for the disableTotp field.
+func (r *mutationResolver) DisableTotp(ctx context.Context, code string) (bool, error) {
+	if err := r.auth.DisableTOTP(repository.ForContext(ctx), code); err != nil {
+		return false, err
 	}
-	repo := repository.NewReservationRepository()
-	now := time.Now()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	return true, nil
+}
+
+// ResetStaffTotp is the resolver for the resetStaffTotp field.
+func (r *mutationResolver) ResetStaffTotp(ctx context.Context, id string) (*model.StaffUser, error) {
+	staff, err := r.auth.ResetStaffTOTP(repository.ForContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	return staff.ToModel(), nil
+}
+
+// SetTotpRequired is the resolver for the setTotpRequired field.
+func (r *mutationResolver) SetTotpRequired(ctx context.Context, required bool) (bool, error) {
+	if err := r.auth.SetTOTPRequired(required); err != nil {
+		return false, err
+	}
+	return required, nil
+}
+
+// CreateAPIKey is the resolver for the createApiKey field.
+func (r *mutationResolver) CreateAPIKey(ctx context.Context, input model.NewAPIKey) (*model.CreatedAPIKey, error) {
+	scopes, err := checkAPIKeyScopes(input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, key, err := r.auth.CreateAPIKey(repository.ForContext(ctx), input.Name, scopes)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreatedAPIKey{APIKey: apiKey.ToModel(nil), Key: key}, nil
+}
+
+// RevokeAPIKey is the resolver for the revokeApiKey field.
+func (r *mutationResolver) RevokeAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	if _, err := r.auth.RevokeAPIKey(id); err != nil {
+		return nil, err
+	}
+	keys, err := r.auth.ListAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.ID == id {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("API-Schlüssel nicht gefunden")
+}
+
+// ResendEmail is the resolver for the resendEmail field.
+func (r *mutationResolver) ResendEmail(ctx context.Context, id string) (*model.OutboxEmail, error) {
+	return r.outbox.Resend(id)
+}
+
+// GetReservation is the resolver for the getReservation field.
+func (r *queryResolver) GetReservation(ctx context.Context, filter model.ReservationFilter) ([]*model.Reservation, error) {
+	return r.store.GetByFilter(filter)
+}
+
+// GetAllReservation is the resolver for the getAllReservation field.
+func (r *queryResolver) GetAllReservation(ctx context.Context) ([]*model.Reservation, error) {
+	return r.store.GetAll()
+}
+
+// GetReservationInfo is the resolver for the getReservationInfo field.
+func (r *queryResolver) GetReservationInfo(ctx context.Context, date *time.Time) (*model.ReservationInfo, error) {
+	return r.store.GetStats(date)
+}
+
+// GetReservationToday returns all reservations for today
+func (r *queryResolver) GetReservationToday(ctx context.Context) ([]*model.Reservation, error) {
+	schedule, err := r.hours.Load()
+	if err != nil {
+		return nil, err
+	}
+	startOfDay, endOfDay := schedule.DayBounds(time.Now())
 
 	filter := model.ReservationFilter{
 		DateFrom: &startOfDay,
 		DateTo:   &endOfDay,
 	}
-	return repo.GetByFilter(filter)
+	return r.store.GetByFilter(filter)
 }
 
 // GetReservationInfoToday returns stats for today
 func (r *queryResolver) GetReservationInfoToday(ctx context.Context) (*model.ReservationInfo, error) {
-	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	repo := repository.NewReservationRepository()
 	now := time.Now()
-	return repo.GetStats(&now)
+	return r.store.GetStats(&now)
 }
 
 // GetReservationBySequence is the resolver for the getReservationBySequence field.
 func (r *queryResolver) GetReservationBySequence(ctx context.Context, sequence int32) ([]*model.Reservation, error) {
-	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
+	// The sequence indexes today's slot grid across all service periods
+	schedule, err := r.hours.Load()
+	if err != nil {
+		return nil, err
 	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
+	slots := schedule.DaySlots(time.Now())
+	if sequence < 0 || int(sequence) >= len(slots) {
+		return []*model.Reservation{}, nil
 	}
-	repo := repository.NewReservationRepository()
-
-	// Base start time at 17:00 today
-	now := time.Now()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 17, 0, 0, 0, time.UTC)
-
-	// Calculate start and end of the sequence
-	start := startOfDay.Add(time.Duration(sequence) * 30 * time.Minute)
-	end := start.Add(30 * time.Minute)
+	start, end := slots[sequence].Start, slots[sequence].End
 
 	filter := model.ReservationFilter{
 		DateFrom: &start,
 		DateTo:   &end,
 	}
 
-	return repo.GetByFilter(filter)
+	return r.store.GetByFilter(filter)
 }
 
 // GetBigReservation is the resolver for the getBigReservation field.
 func (r *queryResolver) GetBigReservation(ctx context.Context) ([]*model.Reservation, error) {
-	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
-	}
-
 	amount := int32(5)
 
-	repo := repository.NewReservationRepository()
 	filter := model.ReservationFilter{
 		Amount: &amount, // 5 or more persons
 	}
-	all, err := repo.GetAllByFilter(filter)
+	all, err := r.store.GetAllByFilter(filter)
 	if err != nil {
 		return nil, err
 	}
@@ -303,26 +491,129 @@ func (r *queryResolver) GetBigReservation(ctx context.Context) ([]*model.Reserva
 
 // GetAllReservationWithFilter is the resolver for the getAllReservationWithFilter field.
 func (r *queryResolver) GetAllReservationWithFilter(ctx context.Context, filter model.ReservationFilter) ([]*model.Reservation, error) {
+	return r.store.GetAllByFilter(filter)
+}
+
+// GetArchivedReservation is the resolver for the getArchivedReservation field.
+func (r *queryResolver) GetArchivedReservation(ctx context.Context, filter model.ReservationFilter) ([]*model.Reservation, error) {
+	return r.store.GetArchivedByFilter(filter)
+}
+
+// Availability is the resolver for the availability field.
+func (r *queryResolver) Availability(ctx context.Context, date time.Time, partySize int32) ([]*model.AvailableSlot, error) {
+	return r.store.GetAvailability(date, partySize)
+}
+
+// OpeningHours is the resolver for the openingHours field.
+func (r *queryResolver) OpeningHours(ctx context.Context) (*model.OpeningHours, error) {
+	return r.hours.Get()
+}
+
+// Closures is the resolver for the closures field.
+func (r *queryResolver) Closures(ctx context.Context, from *time.Time, to *time.Time) ([]*model.Closure, error) {
+	return r.hours.ListClosures(from, to)
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model.StaffUser, error) {
 	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
+	if user == nil || !user.IsAdmin {
+		return nil, nil
 	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
+	staff, err := r.staff.GetByID(user.StaffID)
+	if err != nil {
+		return nil, err
 	}
-	repo := repository.NewReservationRepository()
-	return repo.GetAllByFilter(filter)
+	return staff.ToModel(), nil
 }
 
-// ReservationUpdated is the resolver for the reservationUpdated field.
-func (r *subscriptionResolver) ReservationUpdated(ctx context.Context) (<-chan *model.ReservationEventPayload, error) {
-	user := repository.ForContext(ctx)
-	if user == nil {
-		return nil, fmt.Errorf("Unauthenticated")
+// StaffUsers is the resolver for the staffUsers field.
+func (r *queryResolver) StaffUsers(ctx context.Context) ([]*model.StaffUser, error) {
+	staff, err := r.staff.List()
+	if err != nil {
+		return nil, err
+	}
+	result := []*model.StaffUser{}
+	for _, s := range staff {
+		result = append(result, s.ToModel())
+	}
+	return result, nil
+}
+
+// Sessions is the resolver for the sessions field.
+func (r *queryResolver) Sessions(ctx context.Context) ([]*model.Session, error) {
+	return r.auth.ListSessions(repository.ForContext(ctx))
+}
+
+// TotpRequired is the resolver for the totpRequired field.
+func (r *queryResolver) TotpRequired(ctx context.Context) (bool, error) {
+	return r.auth.TOTPRequired()
+}
+
+// APIKeys is the resolver for the apiKeys field.
+func (r *queryResolver) APIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	return r.auth.ListAPIKeys()
+}
+
+// EmailOutbox is the resolver for the emailOutbox field.
+func (r *queryResolver) EmailOutbox(ctx context.Context, status model.EmailStatus) ([]*model.OutboxEmail, error) {
+	return r.outbox.List(status)
+}
+
+// PreviewEmail is the resolver for the previewEmail field.
+func (r *queryResolver) PreviewEmail(ctx context.Context, reservationID string, event model.ReservationEventBroadcast) (*model.EmailPreview, error) {
+	reservation, err := r.store.GetByID(reservationID)
+	if err != nil {
+		return nil, err
 	}
-	if !user.IsAdmin {
-		return nil, fmt.Errorf("Unauthenticated")
+	email, err := r.mailer.RenderReservationEmail(reservation, event)
+	if err != nil {
+		return nil, err
 	}
+	return &model.EmailPreview{Subject: email.Subject, HTML: email.HTML}, nil
+}
+
+// APIKeyScopes is the resolver for the apiKeyScopes field.
+func (r *queryResolver) APIKeyScopes(ctx context.Context) ([]string, error) {
+	return apiKeyScopes(), nil
+}
+
+// MyReservations is the resolver for the myReservations field.
+func (r *queryResolver) MyReservations(ctx context.Context) ([]*model.Reservation, error) {
+	user := repository.ForContext(ctx)
+	if user.Email != "" {
+		return r.store.GetUpcomingByEmail(user.Email)
+	}
+	result := []*model.Reservation{}
+	if user.ReservationID != "" {
+		reservation, err := r.store.GetByID(user.ReservationID)
+		if err != nil {
+			return nil, err
+		}
+		if reservation != nil {
+			result = append(result, reservation)
+		}
+	}
+	return result, nil
+}
+
+// History is the resolver for the history field.
+func (r *reservationResolver) History(ctx context.Context, obj *model.Reservation) ([]*model.ReservationEvent, error) {
+	return r.store.History(obj.ID)
+}
+
+// NotificationChannels is the resolver for the notificationChannels field.
+func (r *reservationResolver) NotificationChannels(ctx context.Context, obj *model.Reservation) ([]model.NotificationChannel, error) {
+	return r.store.NotificationChannels(obj.ID)
+}
+
+// TotpEnabled is the resolver for the totpEnabled field.
+func (r *staffUserResolver) TotpEnabled(ctx context.Context, obj *model.StaffUser) (bool, error) {
+	return r.auth.TOTPEnabled(obj.ID)
+}
+
+// ReservationUpdated is the resolver for the reservationUpdated field.
+func (r *subscriptionResolver) ReservationUpdated(ctx context.Context) (<-chan *model.ReservationEventPayload, error) {
 	id := uuid.New().String()
 	ch := r.Resolver.subscribe(id)
 	go func() {
@@ -338,9 +629,17 @@ func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Reservation returns ReservationResolver implementation.
+func (r *Resolver) Reservation() ReservationResolver { return &reservationResolver{r} }
+
+// StaffUser returns StaffUserResolver implementation.
+func (r *Resolver) StaffUser() StaffUserResolver { return &staffUserResolver{r} }
+
 // Subscription returns SubscriptionResolver implementation.
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type reservationResolver struct{ *Resolver }
+type staffUserResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
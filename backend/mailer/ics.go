@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"revervation/backend/graph/model"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type CalendarMethod string
+
+const (
+	CalendarRequest CalendarMethod = "REQUEST"
+	CalendarCancel  CalendarMethod = "CANCEL"
+)
+
+// sequenceEpoch is the start of the SEQUENCE counter. Calendars only apply
+// an update with a higher SEQUENCE, so every mail counts the seconds since.
+var sequenceEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// calendarMethod returns which calendar update, if any, goes with the mail
+// of an event.
+func calendarMethod(event model.ReservationEventBroadcast) (CalendarMethod, bool) {
+	switch event {
+	case model.ReservationEventBroadcastConfirmed, model.ReservationEventBroadcastUpdated:
+		return CalendarRequest, true
+	case model.ReservationEventBroadcastCanceled, model.ReservationEventBroadcastDeclined:
+		return CalendarCancel, true
+	}
+	return "", false
+}
+
+// calendarEvent returns an RFC 5545 calendar with one VEVENT for the
+// reservation. The UID only depends on the reservation ID, so later mails
+// update or remove the same calendar entry.
+func (m *Mailer) calendarEvent(reservation *model.Reservation, method CalendarMethod, organizer *mail.Address, now time.Time) []byte {
+	status := "CONFIRMED"
+	switch {
+	case method == CalendarCancel:
+		status = "CANCELLED"
+	case reservation.Status != model.ReservationStatusConfirmed:
+		status = "TENTATIVE"
+	}
+	domain := organizer.Address[strings.LastIndexByte(organizer.Address, '@')+1:]
+	start := reservation.ReserveAt.UTC()
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Yoake//Reservierungen//DE",
+		"METHOD:" + string(method),
+		"BEGIN:VEVENT",
+		"UID:reservation-" + reservation.ID + "@" + domain,
+		fmt.Sprintf("SEQUENCE:%d", int64(now.Sub(sequenceEpoch).Seconds())),
+		"DTSTAMP:" + formatICSTime(now),
+		"DTSTART:" + formatICSTime(start),
+		"DTEND:" + formatICSTime(start.Add(m.config.VisitDuration)),
+		"SUMMARY:" + escapeICSText(fmt.Sprintf("Reservierung für %d Personen", reservation.Amount)),
+		"STATUS:" + status,
+		"ORGANIZER;CN=" + quoteICSParam(organizer.Name) + ":mailto:" + organizer.Address,
+		"ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED:mailto:" + reservation.Email,
+	}
+	if link := reservationLink(reservation); strings.HasPrefix(link, "http") {
+		lines = append(lines, "URL:"+link)
+	}
+	if m.config.Location != "" {
+		lines = append(lines, "LOCATION:"+escapeICSText(m.config.Location))
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+	}
+	return []byte(b.String())
+}
+
+func formatICSTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func quoteICSParam(s string) string {
+	if s == "" {
+		return `""`
+	}
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+// foldICSLine splits a content line into lines of at most 75 octets without
+// breaking a UTF-8 sequence (RFC 5545, section 3.1).
+func foldICSLine(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		b.WriteString(line[:i] + "\r\n ")
+		line = line[i:]
+		limit = 74
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
@@ -2,136 +2,315 @@ package mailer
 
 import (
 	"fmt"
-	"net/smtp"
+	"mime"
+	"net/mail"
+	"net/url"
 	"os"
 	"revervation/backend/graph/model"
+	"strconv"
+	"time"
+)
+
+type TransportKind string
+
+const (
+	TransportSMTP TransportKind = "smtp"
+	TransportLog  TransportKind = "log"
+)
+
+type TLSMode string
+
+const (
+	TLSStartTLS TLSMode = "starttls"
+	TLSImplicit TLSMode = "tls"
+	TLSNone     TLSMode = "none"
 )
 
 type Config struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
-	From     string
+	Transport TransportKind
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	TLS       TLSMode
+	// From and ReplyTo are addresses like "Yoake <reservierung@yoake.de>".
+	From    string
+	ReplyTo string
+	// ListUnsubscribe is the mailto: or https: target of the
+	// List-Unsubscribe header.
+	ListUnsubscribe string
+	// Location and VisitDuration describe the calendar entry sent with
+	// confirmations.
+	Location      string
+	VisitDuration time.Duration
+	// TemplateDir holds mail templates that replace the built-in ones.
+	TemplateDir string
+}
+
+// ConfigFromEnv reads MAIL_TRANSPORT (smtp or log), MAIL_TEMPLATE_DIR,
+// MAIL_REPLY_TO, MAIL_LIST_UNSUBSCRIBE, RESTAURANT_LOCATION, VISIT_DURATION
+// (default 2h) and the SMTP_* settings. MAIL_TRANSPORT defaults to smtp; a
+// server that should only log its mails, e.g. locally without a mail server,
+// has to say so with MAIL_TRANSPORT=log.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Transport:       TransportKind(os.Getenv("MAIL_TRANSPORT")),
+		Host:            os.Getenv("SMTP_HOST"),
+		Username:        os.Getenv("SMTP_USERNAME"),
+		Password:        os.Getenv("SMTP_PASSWORD"),
+		TLS:             TLSMode(os.Getenv("SMTP_TLS")),
+		From:            os.Getenv("SMTP_FROM"),
+		ReplyTo:         os.Getenv("MAIL_REPLY_TO"),
+		ListUnsubscribe: os.Getenv("MAIL_LIST_UNSUBSCRIBE"),
+		TemplateDir:     os.Getenv("MAIL_TEMPLATE_DIR"),
+		Location:        os.Getenv("RESTAURANT_LOCATION"),
+		VisitDuration:   2 * time.Hour,
+	}
+	if cfg.Transport == "" {
+		cfg.Transport = TransportSMTP
+	}
+
+	if v := os.Getenv("VISIT_DURATION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid VISIT_DURATION %q", v)
+		}
+		cfg.VisitDuration = d
+	}
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port <= 0 {
+			return Config{}, fmt.Errorf("invalid SMTP_PORT %q", v)
+		}
+		cfg.Port = port
+	}
+	switch cfg.TLS {
+	case "":
+		cfg.TLS = TLSStartTLS
+		if cfg.Port == 465 {
+			cfg.TLS = TLSImplicit
+		}
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return Config{}, fmt.Errorf("invalid SMTP_TLS %q, expected starttls, tls or none", cfg.TLS)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.TLS == TLSImplicit {
+			cfg.Port = 465
+		}
+	}
+	switch cfg.Transport {
+	case TransportSMTP:
+		if cfg.Host == "" || cfg.From == "" {
+			return Config{}, fmt.Errorf("sending mail needs SMTP_HOST and SMTP_FROM, or MAIL_TRANSPORT=log to only log mails")
+		}
+	case TransportLog:
+	default:
+		return Config{}, fmt.Errorf("invalid MAIL_TRANSPORT %q, expected smtp or log", cfg.Transport)
+	}
+	if cfg.From == "" {
+		cfg.From = "noreply@localhost"
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SMTP_FROM %q: %w", cfg.From, err)
+	}
+	unsubscribe := from.Address
+	if cfg.ReplyTo != "" {
+		replyTo, err := mail.ParseAddress(cfg.ReplyTo)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid MAIL_REPLY_TO %q: %w", cfg.ReplyTo, err)
+		}
+		unsubscribe = replyTo.Address
+	}
+	if cfg.ListUnsubscribe == "" {
+		cfg.ListUnsubscribe = "mailto:" + unsubscribe + "?subject=Abmelden"
+	}
+	return cfg, nil
+}
+
+// Timezone returns the timezone of the restaurant. Times in mails are
+// written in it, whatever the timezone of the server.
+type Timezone interface {
+	Location() (*time.Location, error)
 }
 
 type Mailer struct {
-	config Config
-	auth   smtp.Auth
+	config    Config
+	transport Transport
+	templates *Templates
+	timezone  Timezone
+}
+
+func NewMailer(cfg Config, transport Transport, templates *Templates, timezone Timezone) *Mailer {
+	return &Mailer{config: cfg, transport: transport, templates: templates, timezone: timezone}
+}
+
+// Email is a rendered mail.
+type Email struct {
+	Subject string
+	HTML    string
 }
 
-func NewMailer(cfg Config) *Mailer {
-	auth := smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
-	return &Mailer{config: cfg, auth: auth}
+// RenderReservationEmail renders the mail of an event without sending it.
+func (m *Mailer) RenderReservationEmail(reservation *model.Reservation, event model.ReservationEventBroadcast) (*Email, error) {
+	data, err := m.reservationData(reservation)
+	if err != nil {
+		return nil, err
+	}
+	return m.render(eventTemplate(event), data)
 }
 
 func (m *Mailer) SendReservationStatusEmail(reservation *model.Reservation, event model.ReservationEventBroadcast) error {
-	to := reservation.Email
-	subject := fmt.Sprintf("Ihre Reservierung wurde %s", formatEvent(event))
-	body := m.buildHTMLBody(reservation, event)
-	msg := fmt.Sprintf("From: %s\r\n", m.config.From) +
-		fmt.Sprintf("To: %s\r\n", to) +
-		fmt.Sprintf("Subject: %s\r\n", subject) +
-		"MIME-version: 1.0;\r\nContent-Type: text/html; charset=\"UTF-8\";\r\n\r\n" +
-		body
-	addr := fmt.Sprintf("%s:%d", m.config.Host, m.config.Port)
-	return smtp.SendMail(addr, m.auth, m.config.From, []string{to}, []byte(msg))
-}
-
-// SendCustomHTMLEmail sends an email with custom HTML content using the same layout
+	email, err := m.RenderReservationEmail(reservation, event)
+	if err != nil {
+		return err
+	}
+	return m.sendWithCalendar(reservation, event, email)
+}
+
+// SendCustomHTMLEmail sends a message written by staff in the usual layout.
+// Only the formatting tags allowed by SanitizeHTML are kept.
 func (m *Mailer) SendCustomHTMLEmail(reservation *model.Reservation, customHTML string) error {
-	event := model.ReservationEventBroadcastDeclined
-	to := reservation.Email
-	subject := fmt.Sprintf("Ihre Reservierung wurde %s", formatEvent(event))
-	body := m.buildCustomHTMLBody(reservation, customHTML)
-	msg := fmt.Sprintf("From: %s\r\n", m.config.From) +
-		fmt.Sprintf("To: %s\r\n", to) +
-		fmt.Sprintf("Subject: %s\r\n", subject) +
-		"MIME-version: 1.0;\r\nContent-Type: text/html; charset=\"UTF-8\";\r\n\r\n" +
-		body
-	addr := fmt.Sprintf("%s:%d", m.config.Host, m.config.Port)
-	return smtp.SendMail(addr, m.auth, m.config.From, []string{to}, []byte(msg))
-}
-
-func formatEvent(event model.ReservationEventBroadcast) string {
-	switch event {
-	case model.ReservationEventBroadcastConfirmed:
-		return "bestätigt"
-	case model.ReservationEventBroadcastCanceled:
-		return "storniert"
-	case model.ReservationEventBroadcastDeclined:
-		return "abgelehnt"
-	case model.ReservationEventBroadcastCreated:
-		return "erfasst"
-	case model.ReservationEventBroadcastUpdated:
-		return "aktualisiert"
-	default:
-		return "aktualisiert"
-	}
-}
-
-func (m *Mailer) buildHTMLBody(reservation *model.Reservation, event model.ReservationEventBroadcast) string {
-	notes := "Keine"
-	if reservation.Notes != nil && *reservation.Notes != "" {
-		notes = *reservation.Notes
-	}
-	var statusMessage string
-	switch event {
-	case model.ReservationEventBroadcastConfirmed:
-		statusMessage = "Ihre Reservierung wurde bestätigt. Wir freuen uns, Sie begrüßen zu dürfen!"
-	case model.ReservationEventBroadcastCanceled:
-		statusMessage = "Leider wurde Ihre Reservierung storniert. Wir entschuldigen uns für die Unannehmlichkeiten."
-	case model.ReservationEventBroadcastDeclined:
-		statusMessage = "Ihre Reservierung wurde abgelehnt."
-	case model.ReservationEventBroadcastCreated:
-		statusMessage = "Ihre Reservierung wurde erfolgreich erfasst."
-	case model.ReservationEventBroadcastUpdated:
-		statusMessage = "Ihre Reservierung wurde aktualisiert."
-	default:
-		statusMessage = "Ihre Reservierung wurde aktualisiert."
-	}
-	return m.wrapHTML(reservation, statusMessage, notes)
-}
-
-func (m *Mailer) buildCustomHTMLBody(reservation *model.Reservation, contentHTML string) string {
-	notes := "Keine"
-	if reservation.Notes != nil && *reservation.Notes != "" {
-		notes = *reservation.Notes
-	}
-	return m.wrapHTML(reservation, contentHTML, notes)
-}
-
-func (m *Mailer) wrapHTML(reservation *model.Reservation, message string, notes string) string {
-	return fmt.Sprintf(`
-		<html>
-		<head>
-		<style>
-		body { font-family: Arial, sans-serif; background-color: #f9f9f9; color: #333; }
-		.container { max-width: 600px; margin: 20px auto; background: #fff; padding: 20px; border-radius: 8px; }
-		h2 { color: #2c3e50; }
-		p { line-height: 1.5; }
-		.footer { margin-top: 20px; font-size: 0.85em; color: #999; }
-		.status { font-weight: bold; color: #e67e22; }
-		</style>
-		</head>
-		<body>
-		<div class="container">
-		<h2>Hallo %s %s,</h2>
-		<p class="status">%s</p>
-		<p><strong>Reservierungsdetails:</strong></p>
-		<ul>
-		<li>Datum & Uhrzeit: %s</li>
-		<li>Anzahl Personen: %d</li>
-		<li>Notizen: %s</li>
-		<br/>
-		<a href="%s/reservation?id=%s">Link zur Reservierung</a>
-		</ul>
-		<p>Vielen Dank für Ihre Reservierung!</p>
-		<div class="footer">
-		Ihr Yoake Restaurant-Team
-		</div>
-		</div>
-		</body>
-		</html>
-		`, *reservation.FirstName, reservation.LastName, message, reservation.ReserveAt.Local().Format("02.01.2006 15:04"), reservation.Amount, notes, os.Getenv("FRONT_END_URI"), reservation.ID)
+	data, err := m.reservationData(reservation)
+	if err != nil {
+		return err
+	}
+	data.Message = SanitizeHTML(customHTML)
+	email, err := m.render(messageTemplate, data)
+	if err != nil {
+		return err
+	}
+	return m.sendWithCalendar(reservation, model.ReservationEventBroadcastDeclined, email)
+}
+
+// SendGuestLoginLink mails a guest the link that signs them in to see their
+// upcoming reservations.
+func (m *Mailer) SendGuestLoginLink(to string, token string) error {
+	link := fmt.Sprintf("%s/reservation/login?token=%s", os.Getenv("FRONT_END_URI"), url.QueryEscape(token))
+	email, err := m.render(guestLoginTemplate, TemplateData{Link: link})
+	if err != nil {
+		return err
+	}
+	return m.send(to, email)
+}
+
+// SendStaffPasswordReset mails a staff member the link to set a new password
+// after their password was reset.
+func (m *Mailer) SendStaffPasswordReset(to, name, token string) error {
+	link := fmt.Sprintf("%s/admin/password?token=%s", os.Getenv("FRONT_END_URI"), url.QueryEscape(token))
+	email, err := m.render(staffResetTemplate, TemplateData{FirstName: name, Link: link})
+	if err != nil {
+		return err
+	}
+	return m.send(to, email)
+}
+
+// SendReminder reminds a guest of their visit. The token lets them confirm
+// or cancel with one click.
+func (m *Mailer) SendReminder(reservation *model.Reservation, token string) error {
+	data, err := m.reservationData(reservation)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/reservation/reminder?token=%s", os.Getenv("FRONT_END_URI"), url.QueryEscape(token))
+	data.ConfirmLink = link + "&action=confirm"
+	data.CancelLink = link + "&action=cancel"
+	email, err := m.render(reminderTemplate, data)
+	if err != nil {
+		return err
+	}
+	return m.send(reservation.Email, email)
+}
+
+func (m *Mailer) render(name string, data TemplateData) (*Email, error) {
+	subject, body, err := m.templates.Render(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Email{Subject: subject, HTML: body}, nil
+}
+
+// send builds the message of a rendered mail and hands it to the transport.
+func (m *Mailer) send(to string, email *Email) error {
+	msg, err := m.message(to, email)
+	if err != nil {
+		return err
+	}
+	return m.deliver(msg)
+}
+
+// sendWithCalendar attaches the calendar entry of the reservation when the
+// event adds, changes or removes it.
+func (m *Mailer) sendWithCalendar(reservation *model.Reservation, event model.ReservationEventBroadcast, email *Email) error {
+	msg, err := m.message(reservation.Email, email)
+	if err != nil {
+		return err
+	}
+	if method, ok := calendarMethod(event); ok {
+		msg.Attachments = append(msg.Attachments, Attachment{
+			Filename:    "reservierung.ics",
+			ContentType: mime.FormatMediaType("text/calendar", map[string]string{"charset": "utf-8", "method": string(method)}),
+			Data:        m.calendarEvent(reservation, method, msg.From, time.Now()),
+		})
+	}
+	return m.deliver(msg)
+}
+
+func (m *Mailer) deliver(msg *Mail) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	return m.transport.Send(&Message{From: msg.From.Address, To: []string{msg.To[0].Address}, Data: data})
+}
+
+func (m *Mailer) message(to string, email *Email) (*Mail, error) {
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", m.config.From, err)
+	}
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+	msg := &Mail{
+		From:            from,
+		To:              []*mail.Address{recipient},
+		Subject:         email.Subject,
+		Text:            htmlToText(email.HTML),
+		HTML:            email.HTML,
+		ListUnsubscribe: m.config.ListUnsubscribe,
+	}
+	if m.config.ReplyTo != "" {
+		if msg.ReplyTo, err = mail.ParseAddress(m.config.ReplyTo); err != nil {
+			return nil, fmt.Errorf("invalid reply-to %q: %w", m.config.ReplyTo, err)
+		}
+	}
+	return msg, nil
+}
+
+func (m *Mailer) reservationData(reservation *model.Reservation) (TemplateData, error) {
+	loc, err := m.timezone.Location()
+	if err != nil {
+		return TemplateData{}, err
+	}
+	data := TemplateData{
+		LastName:  reservation.LastName,
+		ReserveAt: reservation.ReserveAt.In(loc).Format("02.01.2006 15:04"),
+		Amount:    reservation.Amount,
+		Link:      reservationLink(reservation),
+	}
+	if reservation.FirstName != nil {
+		data.FirstName = *reservation.FirstName
+	}
+	if reservation.Notes != nil {
+		data.Notes = *reservation.Notes
+	}
+	return data, nil
+}
+
+func reservationLink(reservation *model.Reservation) string {
+	return fmt.Sprintf("%s/reservation?id=%s", os.Getenv("FRONT_END_URI"), reservation.ID)
 }
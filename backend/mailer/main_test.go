@@ -0,0 +1,42 @@
+package mailer
+
+import "testing"
+
+func TestConfigFromEnvTransport(t *testing.T) {
+	cases := []struct {
+		name      string
+		transport string
+		host      string
+		want      TransportKind
+		wantErr   bool
+	}{
+		{name: "smtp by default", host: "smtp.example", want: TransportSMTP},
+		{name: "smtp without host", wantErr: true},
+		{name: "log", transport: "log", want: TransportLog},
+		{name: "file", transport: "file", wantErr: true},
+		{name: "memory", transport: "memory", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("MAIL_TRANSPORT", tc.transport)
+			t.Setenv("SMTP_HOST", tc.host)
+			t.Setenv("SMTP_FROM", "reservierung@yoake.example")
+			cfg, err := ConfigFromEnv()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("got %+v, want an error", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Transport != tc.want {
+				t.Errorf("got transport %q, want %q", cfg.Transport, tc.want)
+			}
+			if _, err := NewTransport(cfg); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Attachment is a file sent along with a mail.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Mail is a multipart/alternative message with a plain text and an HTML
+// body, or a plain text message when HTML is empty, wrapped in
+// multipart/mixed when it has attachments. Date, MessageID and Boundary are
+// generated when left empty.
+type Mail struct {
+	From            *mail.Address
+	To              []*mail.Address
+	ReplyTo         *mail.Address
+	Subject         string
+	Text            string
+	HTML            string
+	ListUnsubscribe string
+	Attachments     []Attachment
+	Date            time.Time
+	MessageID       string
+	Boundary        string
+}
+
+// Bytes returns the message in RFC 5322 form with CRLF line endings.
+// Non-ASCII header values are encoded as RFC 2047 encoded-words and both
+// bodies are quoted-printable.
+func (m *Mail) Bytes() ([]byte, error) {
+	if m.From == nil || len(m.To) == 0 {
+		return nil, fmt.Errorf("mail needs a sender and a recipient")
+	}
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	messageID := m.MessageID
+	if messageID == "" {
+		messageID = newMessageID(m.From.Address)
+	}
+	boundary := m.Boundary
+	if boundary == "" {
+		boundary = "alt-" + randomHex(12)
+	}
+
+	header := [][2]string{
+		{"Date", date.Format(time.RFC1123Z)},
+		{"From", m.From.String()},
+		{"To", joinAddresses(m.To)},
+	}
+	if m.ReplyTo != nil {
+		header = append(header, [2]string{"Reply-To", m.ReplyTo.String()})
+	}
+	header = append(header,
+		[2]string{"Subject", mime.QEncoding.Encode("utf-8", m.Subject)},
+		[2]string{"Message-ID", messageID},
+	)
+	if m.ListUnsubscribe != "" {
+		header = append(header, [2]string{"List-Unsubscribe", "<" + m.ListUnsubscribe + ">"})
+	}
+	header = append(header,
+		[2]string{"MIME-Version", "1.0"},
+	)
+	mixed := "mixed-" + boundary
+	if len(m.Attachments) > 0 {
+		header = append(header, [2]string{"Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mixed})})
+	}
+
+	var buf bytes.Buffer
+	for _, h := range header {
+		buf.WriteString(foldHeader(h[0] + ": " + h[1]))
+	}
+	if len(m.Attachments) == 0 {
+		if err := m.writeBody(&buf, boundary); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	buf.WriteString("\r\n--" + mixed + "\r\n")
+	if err := m.writeBody(&buf, boundary); err != nil {
+		return nil, err
+	}
+	for _, attachment := range m.Attachments {
+		buf.WriteString("--" + mixed + "\r\n")
+		buf.WriteString("Content-Type: " + attachment.ContentType + "\r\n")
+		buf.WriteString("Content-Disposition: " + mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}) + "\r\n")
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64(&buf, attachment.Data)
+	}
+	buf.WriteString("--" + mixed + "--\r\n")
+	return buf.Bytes(), nil
+}
+
+// writeBody writes the header and content of the body: the text alone, or
+// both bodies as multipart/alternative.
+func (m *Mail) writeBody(buf *bytes.Buffer, boundary string) error {
+	if m.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(buf, m.Text); err != nil {
+			return err
+		}
+		buf.WriteString("\r\n")
+		return nil
+	}
+	buf.WriteString(foldHeader("Content-Type: " + mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": boundary})))
+	buf.WriteString("\r\n")
+	return m.writeAlternative(buf, boundary)
+}
+
+func (m *Mail) writeAlternative(buf *bytes.Buffer, boundary string) error {
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		buf.WriteString("--" + boundary + "\r\n")
+		buf.WriteString("Content-Type: " + part.contentType + "\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(buf, part.body); err != nil {
+			return err
+		}
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("--" + boundary + "--\r\n")
+	return nil
+}
+
+// writeBase64 writes data in lines of 76 characters.
+func writeBase64(buf *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
+
+// foldHeader breaks a header line at spaces so lines stay below 78
+// characters where possible (RFC 5322, section 2.2.3).
+func foldHeader(line string) string {
+	var b strings.Builder
+	for len(line) > 76 {
+		i := strings.LastIndexByte(line[:76], ' ')
+		if i <= 0 {
+			break
+		}
+		b.WriteString(line[:i] + "\r\n")
+		line = line[i:]
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
+
+func writeQuotedPrintable(buf *bytes.Buffer, body string) error {
+	w := quotedprintable.NewWriter(buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func joinAddresses(addresses []*mail.Address) string {
+	parts := make([]string, len(addresses))
+	for i, address := range addresses {
+		parts[i] = address.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		domain = from[i+1:]
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), randomHex(8), domain)
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
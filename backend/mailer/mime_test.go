@@ -0,0 +1,100 @@
+package mailer
+
+import (
+	"bytes"
+	"flag"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"revervation/backend/graph/model"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+type fixedTimezone struct{ loc *time.Location }
+
+func (z fixedTimezone) Location() (*time.Location, error) { return z.loc, nil }
+
+func TestMailBytes(t *testing.T) {
+	t.Setenv("FRONT_END_URI", "https://yoake.example")
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := NewMailer(Config{VisitDuration: 2 * time.Hour, Location: "Hauptstraße 1, Köln"}, nil, nil, fixedTimezone{berlin})
+
+	from := &mail.Address{Name: "Yoake Köln", Address: "reservierung@yoake.example"}
+	to := []*mail.Address{{Name: "Erika Müller", Address: "erika@example.com"}}
+	date := time.Date(2026, 3, 14, 9, 30, 0, 0, berlin)
+	firstName := "Erika"
+	reservation := &model.Reservation{
+		ID:        "6f1c2a9e-0b8d-4c1e-9f3a-2d7e5b4a1c00",
+		FirstName: &firstName,
+		LastName:  "Müller",
+		Email:     "erika@example.com",
+		Amount:    4,
+		ReserveAt: time.Date(2026, 3, 20, 19, 0, 0, 0, berlin),
+		Status:    model.ReservationStatusConfirmed,
+	}
+	html := `<p>Guten Tag Frau Müller,</p><p>Ihre Reservierung für 4 Personen am 20.03.2026 um 19:00 Uhr ist bestätigt.</p>`
+
+	cases := []struct {
+		name string
+		mail Mail
+	}{
+		{"text_only", Mail{
+			Subject: "Ihr Anmeldelink",
+			Text:    "Guten Tag,\n\nüber diesen Link sehen Sie Ihre Reservierungen:\nhttps://yoake.example/reservation/login?token=abc\n",
+		}},
+		{"alternative", Mail{
+			ReplyTo:         &mail.Address{Address: "team@yoake.example"},
+			Subject:         "Reservierung bestätigt – Samstag, 20.03.",
+			Text:            htmlToText(html),
+			HTML:            html,
+			ListUnsubscribe: "https://yoake.example/unsubscribe",
+		}},
+		{"mixed_ics", Mail{
+			Subject: "Reservierung bestätigt",
+			Text:    htmlToText(html),
+			HTML:    html,
+			Attachments: []Attachment{{
+				Filename:    "reservierung.ics",
+				ContentType: mime.FormatMediaType("text/calendar", map[string]string{"charset": "utf-8", "method": string(CalendarRequest)}),
+				Data:        mailer.calendarEvent(reservation, CalendarRequest, from, date),
+			}},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := tc.mail
+			msg.From, msg.To = from, to
+			msg.Date = date
+			msg.MessageID = "<" + tc.name + "@yoake.example>"
+			msg.Boundary = "alt-" + tc.name
+
+			got, err := msg.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", tc.name+".eml")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("message differs from %s (run with -update to accept it):\n%s", golden, got)
+			}
+			if _, err := mail.ReadMessage(bytes.NewReader(got)); err != nil {
+				t.Errorf("message does not parse: %v", err)
+			}
+		})
+	}
+}
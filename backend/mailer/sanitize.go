@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedTags are the formatting tags staff may use in a custom message.
+var allowedTags = map[string]bool{
+	"p": true, "br": true, "strong": true, "b": true, "em": true, "i": true, "u": true,
+	"ul": true, "ol": true, "li": true, "a": true, "h3": true, "h4": true, "span": true, "div": true,
+}
+
+// droppedTags are removed together with their content.
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "template": true, "head": true, "title": true, "textarea": true,
+}
+
+// SanitizeHTML keeps the allowed formatting tags of a custom message and
+// drops everything else: scripts, styles, event handlers and all attributes
+// except an http, https, mailto or tel href on links. Unclosed tags are
+// closed so the message cannot break the surrounding layout.
+func SanitizeHTML(s string) template.HTML {
+	z := html.NewTokenizer(strings.NewReader(s))
+	var b strings.Builder
+	var open []string
+	dropped := 0
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			for i := len(open) - 1; i >= 0; i-- {
+				b.WriteString("</" + open[i] + ">")
+			}
+			return template.HTML(b.String())
+		case html.TextToken:
+			if dropped == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if droppedTags[tok.Data] {
+				if tt == html.StartTagToken {
+					dropped++
+				}
+				continue
+			}
+			if dropped > 0 || !allowedTags[tok.Data] {
+				continue
+			}
+			b.WriteString("<" + tok.Data)
+			if tok.Data == "a" {
+				for _, attr := range tok.Attr {
+					if attr.Key == "href" && safeURL(attr.Val) {
+						b.WriteString(` href="` + html.EscapeString(attr.Val) + `"`)
+					}
+				}
+			}
+			b.WriteString(">")
+			if tok.Data != "br" && tt == html.StartTagToken {
+				open = append(open, tok.Data)
+			}
+		case html.EndTagToken:
+			tok := z.Token()
+			if droppedTags[tok.Data] {
+				if dropped > 0 {
+					dropped--
+				}
+				continue
+			}
+			if dropped > 0 {
+				continue
+			}
+			// Close everything opened after the tag; ignore stray end tags.
+			i := len(open) - 1
+			for i >= 0 && open[i] != tok.Data {
+				i--
+			}
+			if i < 0 {
+				continue
+			}
+			for j := len(open) - 1; j >= i; j-- {
+				b.WriteString("</" + open[j] + ">")
+			}
+			open = open[:i]
+		}
+	}
+}
+
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto", "tel":
+		return true
+	}
+	return false
+}
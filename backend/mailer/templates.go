@@ -0,0 +1,216 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"revervation/backend/graph/model"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+const (
+	layoutTemplate     = "layout.html"
+	messageTemplate    = "message.html"
+	guestLoginTemplate = "guest_login.html"
+	reminderTemplate   = "reminder.html"
+	staffResetTemplate = "staff_password_reset.html"
+)
+
+// TemplateData is what the mail templates can use.
+type TemplateData struct {
+	FirstName string
+	LastName  string
+	ReserveAt string
+	Amount    int32
+	Notes     string
+	// Link points to the reservation, or to the login for guest_login.html
+	// and the password form for staff_password_reset.html.
+	Link string
+	// Message is the sanitized custom message of message.html.
+	Message template.HTML
+	// ConfirmLink and CancelLink are the answers offered by reminder.html.
+	ConfirmLink string
+	CancelLink  string
+}
+
+// Templates renders the mails with html/template. Every mail is layout.html
+// combined with one page, e.g. confirmed.html, which defines "subject" and
+// either "status" or the whole "content". A page in the template directory
+// replaces the built-in page of the same name.
+type Templates struct {
+	dir   string
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+func NewTemplates(dir string) (*Templates, error) {
+	t := &Templates{dir: dir}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// eventTemplate returns the page of an event, e.g. no_show.html.
+func eventTemplate(event model.ReservationEventBroadcast) string {
+	return strings.ToLower(string(event)) + ".html"
+}
+
+func templatePages() []string {
+	// The page of GUEST_LOGIN is guest_login.html.
+	pages := []string{messageTemplate, reminderTemplate, staffResetTemplate}
+	for _, event := range model.AllReservationEventBroadcast {
+		pages = append(pages, eventTemplate(event))
+	}
+	return pages
+}
+
+// Reload parses all templates again. A broken template is reported and the
+// previous templates stay in use.
+func (t *Templates) Reload() error {
+	layout, err := t.parse(template.New("mail"), layoutTemplate)
+	if err != nil {
+		return err
+	}
+	sample := TemplateData{
+		FirstName:   "Erika",
+		LastName:    "Mustermann",
+		ReserveAt:   "24.12.2025 19:00",
+		Amount:      2,
+		Link:        "https://example.com",
+		ConfirmLink: "https://example.com",
+		CancelLink:  "https://example.com",
+	}
+
+	pages := make(map[string]*template.Template)
+	for _, name := range templatePages() {
+		clone, err := layout.Clone()
+		if err != nil {
+			return err
+		}
+		page, err := t.parse(clone, name)
+		if err != nil {
+			return err
+		}
+		if _, _, err := render(page, sample); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		pages[name] = page
+	}
+
+	t.mu.Lock()
+	t.pages = pages
+	t.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the templates whenever a file in the template directory
+// changes, until ctx is done.
+func (t *Templates) Watch(ctx context.Context, interval time.Duration) {
+	last := t.lastModified()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modified := t.lastModified()
+			if modified.Equal(last) {
+				continue
+			}
+			last = modified
+			if err := t.Reload(); err != nil {
+				log.Printf("Keeping previous mail templates: %v", err)
+				continue
+			}
+			log.Printf("Reloaded mail templates from %s", t.dir)
+		}
+	}
+}
+
+// Render returns the subject and HTML body of a page.
+func (t *Templates) Render(name string, data TemplateData) (string, string, error) {
+	t.mu.RLock()
+	page := t.pages[name]
+	t.mu.RUnlock()
+	if page == nil {
+		return "", "", fmt.Errorf("unknown mail template %q", name)
+	}
+	return render(page, data)
+}
+
+func render(page *template.Template, data TemplateData) (string, string, error) {
+	var buf bytes.Buffer
+	if err := page.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", "", err
+	}
+	// The subject is a header, not HTML: undo the escaping and keep it on
+	// one line.
+	subject := strings.Join(strings.Fields(html.UnescapeString(buf.String())), " ")
+
+	buf.Reset()
+	if err := page.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return "", "", err
+	}
+	return subject, buf.String(), nil
+}
+
+func (t *Templates) parse(tmpl *template.Template, name string) (*template.Template, error) {
+	data, err := t.read(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.Parse(string(data)); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func (t *Templates) read(name string) ([]byte, error) {
+	if t.dir != "" {
+		data, err := os.ReadFile(filepath.Join(t.dir, name))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return defaultTemplates.ReadFile("templates/" + name)
+}
+
+func (t *Templates) lastModified() time.Time {
+	// The directory itself changes when a page is added or removed.
+	var last time.Time
+	if info, err := os.Stat(t.dir); err == nil {
+		last = info.ModTime()
+	}
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return last
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || filepath.Ext(entry.Name()) != ".html" {
+			continue
+		}
+		if info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+	return last
+}
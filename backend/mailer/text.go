@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockTags start on a new line in the text version of a mail.
+var blockTags = map[string]bool{
+	"p": true, "div": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"ul": true, "ol": true, "li": true, "tr": true,
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// htmlToText turns a rendered mail into its plain text alternative. Links
+// keep their target in brackets, list items get a dash.
+func htmlToText(s string) string {
+	z := html.NewTokenizer(strings.NewReader(s))
+	var b strings.Builder
+	var href string
+	skipped := 0
+
+	newline := func(n int) {
+		text := b.String()
+		trailing := len(text) - len(strings.TrimRight(text, "\n"))
+		for ; trailing < n && b.Len() > 0; trailing++ {
+			b.WriteString("\n")
+		}
+	}
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			text := blankLines.ReplaceAllString(b.String(), "\n\n")
+			return strings.TrimSpace(text) + "\n"
+		case html.TextToken:
+			if skipped > 0 {
+				continue
+			}
+			text := strings.Join(strings.Fields(string(z.Text())), " ")
+			if text == "" {
+				continue
+			}
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") && !strings.HasSuffix(b.String(), " ") {
+				b.WriteString(" ")
+			}
+			b.WriteString(text)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch {
+			case tok.Data == "style" || tok.Data == "script" || tok.Data == "head":
+				if tt == html.StartTagToken {
+					skipped++
+				}
+			case tok.Data == "br":
+				b.WriteString("\n")
+			case tok.Data == "li":
+				newline(1)
+				b.WriteString("- ")
+			case tok.Data == "a":
+				href = ""
+				for _, attr := range tok.Attr {
+					if attr.Key == "href" {
+						href = attr.Val
+					}
+				}
+			case blockTags[tok.Data]:
+				newline(2)
+			}
+		case html.EndTagToken:
+			tok := z.Token()
+			switch {
+			case tok.Data == "style" || tok.Data == "script" || tok.Data == "head":
+				if skipped > 0 {
+					skipped--
+				}
+			case tok.Data == "a" && href != "":
+				b.WriteString(" [" + href + "]")
+				href = ""
+			case tok.Data == "li":
+				newline(1)
+			case blockTags[tok.Data]:
+				newline(2)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Message is a complete RFC 5322 message together with its envelope.
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Transport hands a message over for delivery.
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// NewTransport returns the transport selected by cfg.Transport. The file and
+// memory transports cannot be configured; they are built in code, e.g. by
+// tests.
+func NewTransport(cfg Config) (Transport, error) {
+	switch cfg.Transport {
+	case TransportSMTP:
+		return NewSMTPTransport(cfg), nil
+	case TransportLog:
+		return LogTransport{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+	}
+}
+
+// FileTransport writes every message into a maildir, so mails can be read
+// with any mail client during development.
+type FileTransport struct {
+	dir string
+	seq atomic.Uint64
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileTransport{dir: dir}, nil
+}
+
+func (t *FileTransport) Send(msg *Message) error {
+	host, _ := os.Hostname()
+	name := fmt.Sprintf("%d.%d_%d.%s", time.Now().Unix(), os.Getpid(), t.seq.Add(1), strings.ReplaceAll(host, "/", "_"))
+	tmp := filepath.Join(t.dir, "tmp", name)
+	if err := os.WriteFile(tmp, msg.Data, 0o644); err != nil {
+		return err
+	}
+	// Maildir readers only look at new/, so the rename publishes the mail
+	// in one step.
+	return os.Rename(tmp, filepath.Join(t.dir, "new", name))
+}
+
+// MemoryTransport keeps every message in memory. Tests use it to check which
+// mails an operation produced.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Send(msg *Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = append(t.messages, *msg)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (t *MemoryTransport) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Message(nil), t.messages...)
+}
+
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = nil
+}
+
+// LogTransport only logs the envelope and size of a message. It has to be
+// chosen explicitly with MAIL_TRANSPORT=log, as the outbox counts logged
+// mails as sent.
+type LogTransport struct{}
+
+func (LogTransport) Send(msg *Message) error {
+	log.Printf("Mail from %s to %s (%d bytes) not sent: no mail transport configured", msg.From, strings.Join(msg.To, ", "), len(msg.Data))
+	return nil
+}
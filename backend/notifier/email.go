@@ -0,0 +1,26 @@
+package notifier
+
+import (
+	"revervation/backend/graph/model"
+	"revervation/backend/mailer"
+)
+
+// EmailChannel sends the notifications as mails.
+type EmailChannel struct {
+	mailer *mailer.Mailer
+}
+
+func NewEmailChannel(m *mailer.Mailer) *EmailChannel {
+	return &EmailChannel{mailer: m}
+}
+
+func (c *EmailChannel) Name() model.NotificationChannel {
+	return model.NotificationChannelEmail
+}
+
+func (c *EmailChannel) Send(reservation *model.Reservation, event model.ReservationEventBroadcast, customHTML *string) error {
+	if customHTML != nil {
+		return c.mailer.SendCustomHTMLEmail(reservation, *customHTML)
+	}
+	return c.mailer.SendReservationStatusEmail(reservation, event)
+}
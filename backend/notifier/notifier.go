@@ -0,0 +1,36 @@
+package notifier
+
+import (
+	"fmt"
+	"revervation/backend/graph/model"
+)
+
+// Channel delivers the notifications of one NotificationChannel.
+type Channel interface {
+	Name() model.NotificationChannel
+	// Send tells the guest about event. customHTML is the message staff
+	// wrote, if any; channels that cannot show HTML send their usual text.
+	Send(reservation *model.Reservation, event model.ReservationEventBroadcast, customHTML *string) error
+}
+
+// Notifier hands every message from the outbox to the channel it was queued
+// for.
+type Notifier struct {
+	channels map[model.NotificationChannel]Channel
+}
+
+func NewNotifier(channels ...Channel) *Notifier {
+	n := &Notifier{channels: make(map[model.NotificationChannel]Channel)}
+	for _, channel := range channels {
+		n.channels[channel.Name()] = channel
+	}
+	return n
+}
+
+func (n *Notifier) Notify(channel model.NotificationChannel, reservation *model.Reservation, event model.ReservationEventBroadcast, customHTML *string) error {
+	c, ok := n.channels[channel]
+	if !ok {
+		return fmt.Errorf("no notification channel %s configured", channel)
+	}
+	return c.Send(reservation, event, customHTML)
+}
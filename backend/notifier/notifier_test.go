@@ -0,0 +1,70 @@
+package notifier
+
+import (
+	"revervation/backend/graph/model"
+	"revervation/backend/mailer"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fixedTimezone struct{ loc *time.Location }
+
+func (z fixedTimezone) Location() (*time.Location, error) { return z.loc, nil }
+
+func TestNotifierChannels(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timezone := fixedTimezone{berlin}
+	templates, err := mailer.NewTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := mailer.NewMemoryTransport()
+	mail := mailer.NewMailer(mailer.Config{From: "Yoake <reservierung@yoake.example>", VisitDuration: 2 * time.Hour}, transport, templates, timezone)
+	sms := NewMemoryProvider()
+	notifier := NewNotifier(NewEmailChannel(mail), NewSMSChannel(Config{Sender: "Yoake"}, sms, timezone))
+
+	reservation := &model.Reservation{
+		ID:          "r1",
+		LastName:    "Mustermann",
+		Email:       "erika@example.com",
+		PhoneNumber: "0171 / 123 45 67",
+		Amount:      4,
+		ReserveAt:   time.Date(2026, 3, 20, 18, 0, 0, 0, time.UTC),
+		Status:      model.ReservationStatusConfirmed,
+	}
+
+	if err := notifier.Notify(model.NotificationChannelSms, reservation, model.ReservationEventBroadcastConfirmed, nil); err != nil {
+		t.Fatal(err)
+	}
+	messages := sms.Messages()
+	if len(messages) != 1 || len(transport.Messages()) != 0 {
+		t.Fatalf("SMS channel sent %v and %d mails", messages, len(transport.Messages()))
+	}
+	want := SMS{To: "01711234567", Text: "Yoake: Ihre Reservierung am 20.03. 19:00 Uhr für 4 Personen wurde bestätigt."}
+	if messages[0] != want {
+		t.Errorf("got %+v, want %+v", messages[0], want)
+	}
+
+	if err := notifier.Notify(model.NotificationChannelEmail, reservation, model.ReservationEventBroadcastConfirmed, nil); err != nil {
+		t.Fatal(err)
+	}
+	mails := transport.Messages()
+	if len(mails) != 1 || len(sms.Messages()) != 1 {
+		t.Fatalf("email channel sent %d mails and %d text messages", len(mails), len(sms.Messages()))
+	}
+	if to := strings.Join(mails[0].To, ","); to != reservation.Email {
+		t.Errorf("mail went to %s", to)
+	}
+
+	reservation.PhoneNumber = ""
+	if err := notifier.Notify(model.NotificationChannelSms, reservation, model.ReservationEventBroadcastConfirmed, nil); err == nil {
+		t.Error("SMS without phone number succeeded")
+	}
+	if err := NewNotifier(NewEmailChannel(mail)).Notify(model.NotificationChannelSms, reservation, model.ReservationEventBroadcastConfirmed, nil); err == nil {
+		t.Error("notification on a channel that is not configured succeeded")
+	}
+}
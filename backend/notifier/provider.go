@@ -0,0 +1,168 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type ProviderKind string
+
+const (
+	ProviderHTTP ProviderKind = "http"
+	ProviderLog  ProviderKind = "log"
+)
+
+type Config struct {
+	// Provider is empty when text messages are turned off.
+	Provider ProviderKind
+	// URL and Token configure the HTTP provider.
+	URL   string
+	Token string
+	// Sender is the name the text messages come from.
+	Sender string
+}
+
+// Enabled reports whether the server sends text messages at all.
+func (c Config) Enabled() bool {
+	return c.Provider != ""
+}
+
+// ConfigFromEnv reads SMS_PROVIDER (http or log), SMS_HTTP_URL,
+// SMS_HTTP_TOKEN and SMS_SENDER (default "Yoake"). SMS is optional: without
+// SMS_PROVIDER and SMS_HTTP_URL it stays turned off, and setting only
+// SMS_HTTP_URL selects the http provider.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Provider: ProviderKind(os.Getenv("SMS_PROVIDER")),
+		URL:      os.Getenv("SMS_HTTP_URL"),
+		Token:    os.Getenv("SMS_HTTP_TOKEN"),
+		Sender:   os.Getenv("SMS_SENDER"),
+	}
+	if cfg.Provider == "" && cfg.URL != "" {
+		cfg.Provider = ProviderHTTP
+	}
+	if cfg.Sender == "" {
+		cfg.Sender = "Yoake"
+	}
+	switch cfg.Provider {
+	case "":
+	case ProviderHTTP:
+		if cfg.URL == "" {
+			return Config{}, fmt.Errorf("SMS_PROVIDER=http needs SMS_HTTP_URL")
+		}
+	case ProviderLog:
+	default:
+		return Config{}, fmt.Errorf("invalid SMS_PROVIDER %q, expected http or log", cfg.Provider)
+	}
+	return cfg, nil
+}
+
+// SMSProvider hands a text message to a gateway. to is a phone number with
+// only digits and an optional leading +.
+type SMSProvider interface {
+	SendSMS(to, text string) error
+}
+
+// NewProvider returns the provider selected by cfg.Provider.
+func NewProvider(cfg Config) (SMSProvider, error) {
+	switch cfg.Provider {
+	case ProviderHTTP:
+		return NewHTTPProvider(cfg), nil
+	case ProviderLog:
+		return LogProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", cfg.Provider)
+	}
+}
+
+// HTTPProvider posts every message as JSON {"to", "from", "text"} to a
+// gateway, authenticated with a bearer token. Any status other than 2xx is
+// an error, so the outbox retries the message.
+type HTTPProvider struct {
+	url    string
+	token  string
+	sender string
+	client *http.Client
+}
+
+func NewHTTPProvider(cfg Config) *HTTPProvider {
+	return &HTTPProvider{url: cfg.URL, token: cfg.Token, sender: cfg.Sender, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *HTTPProvider) SendSMS(to, text string) error {
+	body, err := json.Marshal(map[string]string{"to": to, "from": p.sender, "text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms gateway answered %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+type SMS struct {
+	To   string
+	Text string
+}
+
+// MemoryProvider keeps every message in memory instead of sending it. Tests
+// use it; it cannot be selected from the environment.
+type MemoryProvider struct {
+	mu       sync.Mutex
+	messages []SMS
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{}
+}
+
+func (p *MemoryProvider) SendSMS(to, text string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, SMS{To: to, Text: text})
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (p *MemoryProvider) Messages() []SMS {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]SMS(nil), p.messages...)
+}
+
+func (p *MemoryProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = nil
+}
+
+// LogProvider only logs the recipient and length of a message. It has to be
+// chosen explicitly with SMS_PROVIDER=log, as the outbox counts logged
+// messages as sent.
+type LogProvider struct{}
+
+func (LogProvider) SendSMS(to, text string) error {
+	log.Printf("SMS to %s (%d characters) not sent: no SMS provider configured", to, len([]rune(text)))
+	return nil
+}
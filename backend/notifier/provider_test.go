@@ -0,0 +1,106 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	cases := []struct {
+		name    string
+		token   string
+		status  int
+		wantErr bool
+	}{
+		{"accepted", "secret", http.StatusAccepted, false},
+		{"without token", "", http.StatusOK, false},
+		{"gateway error", "secret", http.StatusInternalServerError, true},
+		{"rejected", "secret", http.StatusUnauthorized, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var method, auth, contentType string
+			var payload map[string]string
+			gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method = r.Method
+				auth = r.Header.Get("Authorization")
+				contentType = r.Header.Get("Content-Type")
+				body, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(body, &payload); err != nil {
+					t.Errorf("body %q: %v", body, err)
+				}
+				w.WriteHeader(tc.status)
+				io.WriteString(w, "gateway says no")
+			}))
+			defer gateway.Close()
+
+			provider := NewHTTPProvider(Config{URL: gateway.URL, Token: tc.token, Sender: "Yoake"})
+			err := provider.SendSMS("+491711234567", "Ihre Reservierung wurde bestätigt.")
+
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "gateway says no") {
+					t.Errorf("got %v, want an error with the gateway's answer", err)
+				}
+			} else if err != nil {
+				t.Errorf("got %v", err)
+			}
+			if method != http.MethodPost || contentType != "application/json" {
+				t.Errorf("got %s with %q", method, contentType)
+			}
+			wantAuth := ""
+			if tc.token != "" {
+				wantAuth = "Bearer " + tc.token
+			}
+			if auth != wantAuth {
+				t.Errorf("Authorization %q, want %q", auth, wantAuth)
+			}
+			want := map[string]string{"to": "+491711234567", "from": "Yoake", "text": "Ihre Reservierung wurde bestätigt."}
+			for key, value := range want {
+				if payload[key] != value {
+					t.Errorf("payload %v, want %v", payload, want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	cases := []struct {
+		name     string
+		provider string
+		url      string
+		want     ProviderKind
+		wantErr  bool
+	}{
+		{name: "nothing configured", want: ""},
+		{name: "only a gateway URL", url: "https://sms.example", want: ProviderHTTP},
+		{name: "http with URL", provider: "http", url: "https://sms.example", want: ProviderHTTP},
+		{name: "http without URL", provider: "http", wantErr: true},
+		{name: "log", provider: "log", want: ProviderLog},
+		{name: "memory", provider: "memory", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SMS_PROVIDER", tc.provider)
+			t.Setenv("SMS_HTTP_URL", tc.url)
+			cfg, err := ConfigFromEnv()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("got %+v, want an error", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Provider != tc.want || cfg.Enabled() != (tc.want != "") {
+				t.Errorf("got provider %q, want %q", cfg.Provider, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"fmt"
+	"revervation/backend/graph/model"
+	"strings"
+	"time"
+)
+
+// Timezone returns the timezone of the restaurant, in which the text
+// messages give the time of a reservation.
+type Timezone interface {
+	Location() (*time.Location, error)
+}
+
+// SMSChannel sends the notifications as short text messages through an
+// SMSProvider.
+type SMSChannel struct {
+	provider SMSProvider
+	sender   string
+	timezone Timezone
+}
+
+func NewSMSChannel(cfg Config, provider SMSProvider, timezone Timezone) *SMSChannel {
+	return &SMSChannel{provider: provider, sender: cfg.Sender, timezone: timezone}
+}
+
+func (c *SMSChannel) Name() model.NotificationChannel {
+	return model.NotificationChannelSms
+}
+
+// Send texts the guest about event. A message written by staff is HTML and
+// meant for a mail, so the guest gets the usual text for the event instead.
+func (c *SMSChannel) Send(reservation *model.Reservation, event model.ReservationEventBroadcast, customHTML *string) error {
+	to := normalizePhoneNumber(reservation.PhoneNumber)
+	if to == "" {
+		return fmt.Errorf("Reservierung %s hat keine Telefonnummer", reservation.ID)
+	}
+	loc, err := c.timezone.Location()
+	if err != nil {
+		return err
+	}
+	return c.provider.SendSMS(to, c.text(reservation, event, loc))
+}
+
+func (c *SMSChannel) text(reservation *model.Reservation, event model.ReservationEventBroadcast, loc *time.Location) string {
+	var what string
+	switch event {
+	case model.ReservationEventBroadcastCreated:
+		what = "ist eingegangen und wird geprüft"
+	case model.ReservationEventBroadcastUpdated:
+		what = "wurde geändert"
+	case model.ReservationEventBroadcastConfirmed:
+		what = "wurde bestätigt"
+	case model.ReservationEventBroadcastDeclined:
+		what = "konnte leider nicht angenommen werden"
+	case model.ReservationEventBroadcastCanceled:
+		what = "wurde storniert"
+	default:
+		what = "hat sich geändert"
+	}
+	return fmt.Sprintf("%s: Ihre Reservierung am %s Uhr für %d Personen %s.",
+		c.sender, reservation.ReserveAt.In(loc).Format("02.01. 15:04"), reservation.Amount, what)
+}
+
+// normalizePhoneNumber drops the spaces, dashes and parentheses guests type
+// into phone numbers, keeping the digits and a leading +.
+func normalizePhoneNumber(number string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(number) {
+		if r >= '0' && r <= '9' || r == '+' && i == 0 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// buckets is a set of token buckets sharing one rule, keyed by caller.
+type buckets struct {
+	mu   sync.Mutex
+	rule Rule
+	m    map[string]*bucket
+}
+
+func newBuckets(rule Rule) *buckets {
+	return &buckets{rule: rule, m: map[string]*bucket{}}
+}
+
+func (b *buckets) refillRate() float64 {
+	return float64(b.rule.Burst) / b.rule.Period.Seconds()
+}
+
+// take removes a token for key. When the bucket is empty it returns how long
+// until the next token is available.
+func (b *buckets) take(key string, now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bk, ok := b.m[key]
+	if !ok {
+		bk = &bucket{tokens: float64(b.rule.Burst), last: now}
+		b.m[key] = bk
+	}
+	bk.tokens += now.Sub(bk.last).Seconds() * b.refillRate()
+	if bk.tokens > float64(b.rule.Burst) {
+		bk.tokens = float64(b.rule.Burst)
+	}
+	bk.last = now
+
+	if bk.tokens >= 1 {
+		bk.tokens--
+		return true, 0
+	}
+	wait := (1 - bk.tokens) / b.refillRate()
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// sweep forgets buckets that have refilled completely; they behave exactly
+// like a new one.
+func (b *buckets) sweep(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, bk := range b.m {
+		if now.Sub(bk.last) >= b.rule.Period {
+			delete(b.m, key)
+		}
+	}
+}
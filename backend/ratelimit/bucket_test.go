@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketsRefill(t *testing.T) {
+	b := newBuckets(Rule{Burst: 3, Period: 3 * time.Second})
+	start := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	for i := range 3 {
+		if ok, _ := b.take("a", start); !ok {
+			t.Fatalf("take %d of the burst refused", i+1)
+		}
+	}
+	ok, wait := b.take("a", start)
+	if ok || wait != time.Second {
+		t.Fatalf("empty bucket: got %v, wait %s, want refused with 1s", ok, wait)
+	}
+	if ok, _ := b.take("b", start); !ok {
+		t.Error("other key shares the empty bucket")
+	}
+
+	steps := []struct {
+		after time.Duration
+		ok    bool
+	}{
+		{500 * time.Millisecond, false},
+		{time.Second, true},
+		{time.Second, false},
+		// Refilling stops at the burst, however long the key was idle.
+		{time.Hour, true},
+		{time.Hour, true},
+		{time.Hour, true},
+		{time.Hour, false},
+	}
+	for i, step := range steps {
+		if ok, _ := b.take("a", start.Add(step.after)); ok != step.ok {
+			t.Errorf("step %d after %s: got %v, want %v", i, step.after, ok, step.ok)
+		}
+	}
+}
+
+func TestBucketsSweep(t *testing.T) {
+	b := newBuckets(Rule{Burst: 1, Period: time.Minute})
+	start := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	b.take("a", start)
+
+	b.sweep(start.Add(59 * time.Second))
+	if _, ok := b.m["a"]; !ok {
+		t.Fatal("bucket swept before it refilled")
+	}
+	b.sweep(start.Add(time.Minute))
+	if _, ok := b.m["a"]; ok {
+		t.Error("refilled bucket kept")
+	}
+}
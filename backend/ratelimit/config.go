@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule allows Burst events at once, refilled evenly over Period.
+type Rule struct {
+	Burst  int
+	Period time.Duration
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%d/%s", r.Burst, r.Period)
+}
+
+type Config struct {
+	Enabled bool
+	// Requests limits every HTTP request per client IP.
+	Requests Rule
+	// OperationIP limits each login-like operation per client IP.
+	OperationIP Rule
+	// OperationAccount limits each login-like operation per target account,
+	// so guessing from many addresses is slowed down as well.
+	OperationAccount Rule
+	// After LockoutThreshold failed attempts an account is locked for
+	// LockoutBase, doubling with every further failure up to LockoutMax.
+	LockoutThreshold int
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header
+	// names the client. Requests from anywhere else are taken at face value.
+	TrustedProxies []netip.Prefix
+}
+
+// ConfigFromEnv reads RATE_LIMIT_ENABLED, RATE_LIMIT_REQUESTS,
+// RATE_LIMIT_OPERATION_IP, RATE_LIMIT_OPERATION_ACCOUNT, LOCKOUT_THRESHOLD,
+// LOCKOUT_BASE, LOCKOUT_MAX and TRUSTED_PROXIES. Rules are written as
+// count/duration, e.g. 10/1m; proxies as a comma separated list of addresses
+// or CIDR ranges.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Enabled:          true,
+		Requests:         Rule{Burst: 300, Period: time.Minute},
+		OperationIP:      Rule{Burst: 20, Period: time.Minute},
+		OperationAccount: Rule{Burst: 10, Period: 15 * time.Minute},
+		LockoutThreshold: 5,
+		LockoutBase:      time.Minute,
+		LockoutMax:       time.Hour,
+	}
+
+	if raw := os.Getenv("RATE_LIMIT_ENABLED"); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid RATE_LIMIT_ENABLED %q", raw)
+		}
+		cfg.Enabled = enabled
+	}
+	rules := map[string]*Rule{
+		"RATE_LIMIT_REQUESTS":          &cfg.Requests,
+		"RATE_LIMIT_OPERATION_IP":      &cfg.OperationIP,
+		"RATE_LIMIT_OPERATION_ACCOUNT": &cfg.OperationAccount,
+	}
+	for name, rule := range rules {
+		if raw := os.Getenv(name); raw != "" {
+			parsed, err := parseRule(raw)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+			}
+			*rule = parsed
+		}
+	}
+	if raw := os.Getenv("LOCKOUT_THRESHOLD"); raw != "" {
+		threshold, err := strconv.Atoi(raw)
+		if err != nil || threshold < 1 {
+			return cfg, fmt.Errorf("invalid LOCKOUT_THRESHOLD %q", raw)
+		}
+		cfg.LockoutThreshold = threshold
+	}
+	durations := map[string]*time.Duration{
+		"LOCKOUT_BASE": &cfg.LockoutBase,
+		"LOCKOUT_MAX":  &cfg.LockoutMax,
+	}
+	for name, target := range durations {
+		if raw := os.Getenv(name); raw != "" {
+			d, err := time.ParseDuration(raw)
+			if err != nil || d <= 0 {
+				return cfg, fmt.Errorf("invalid %s %q", name, raw)
+			}
+			*target = d
+		}
+	}
+	if raw := os.Getenv("TRUSTED_PROXIES"); raw != "" {
+		proxies, err := parseProxies(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid TRUSTED_PROXIES %q: %w", raw, err)
+		}
+		cfg.TrustedProxies = proxies
+	}
+	if cfg.LockoutMax < cfg.LockoutBase {
+		return cfg, fmt.Errorf("LOCKOUT_MAX %s is shorter than LOCKOUT_BASE %s", cfg.LockoutMax, cfg.LockoutBase)
+	}
+	return cfg, nil
+}
+
+func parseRule(raw string) (Rule, error) {
+	count, period, ok := strings.Cut(raw, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("expected count/duration")
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || burst < 1 {
+		return Rule{}, fmt.Errorf("count must be a positive number")
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Rule{}, fmt.Errorf("duration must be positive, e.g. 1m")
+	}
+	return Rule{Burst: burst, Period: d}, nil
+}
+
+func parseProxies(raw string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither an address nor a CIDR range", entry)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
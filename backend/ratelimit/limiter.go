@@ -0,0 +1,134 @@
+// Package ratelimit slows down password and name guessing. Every request is
+// limited per client IP, and login-like operations additionally per IP and
+// per target account, with a lockout that grows with repeated failures.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error is returned when a caller has to wait before trying again.
+type Error struct {
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Zu viele Versuche. Bitte versuchen Sie es in %d Sekunden erneut.", e.Seconds())
+}
+
+// Seconds is RetryAfter rounded up to whole seconds, at least one.
+func (e *Error) Seconds() int {
+	return max(1, int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// Extensions is the machine readable part of the GraphQL error.
+func (e *Error) Extensions() map[string]any {
+	return map[string]any{
+		"code":       "RATE_LIMITED",
+		"retryAfter": e.Seconds(),
+	}
+}
+
+type Limiter struct {
+	config   Config
+	requests *buckets
+	ip       *buckets
+	account  *buckets
+	lockout  *lockout
+}
+
+func NewLimiter(cfg Config) *Limiter {
+	return &Limiter{
+		config:   cfg,
+		requests: newBuckets(cfg.Requests),
+		ip:       newBuckets(cfg.OperationIP),
+		account:  newBuckets(cfg.OperationAccount),
+		lockout:  newLockout(cfg.LockoutThreshold, cfg.LockoutBase, cfg.LockoutMax),
+	}
+}
+
+// Allow takes one attempt at operation op for the client ip and the target
+// account, which may be empty when the operation has none.
+func (l *Limiter) Allow(op, ip, account string) error {
+	if !l.config.Enabled {
+		return nil
+	}
+	now := time.Now()
+	if wait := l.lockout.check(lockoutKey(op, ip, account), now); wait > 0 {
+		return &Error{RetryAfter: wait}
+	}
+	if ok, wait := l.ip.take(op+"|"+ip, now); !ok {
+		return &Error{RetryAfter: wait}
+	}
+	if account != "" {
+		if ok, wait := l.account.take(op+"|"+account, now); !ok {
+			return &Error{RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// Failed records a failed attempt towards the lockout of the account, or of
+// the ip when the operation has no account.
+func (l *Limiter) Failed(op, ip, account string) {
+	if l.config.Enabled {
+		l.lockout.fail(lockoutKey(op, ip, account), time.Now())
+	}
+}
+
+// Succeeded clears the failed attempts of the account.
+func (l *Limiter) Succeeded(op, ip, account string) {
+	l.lockout.reset(lockoutKey(op, ip, account))
+}
+
+func lockoutKey(op, ip, account string) string {
+	if account == "" {
+		return op + "|ip|" + ip
+	}
+	return op + "|account|" + account
+}
+
+// Sweep drops state that no longer limits anybody.
+func (l *Limiter) Sweep(now time.Time) {
+	l.requests.sweep(now)
+	l.ip.sweep(now)
+	l.account.sweep(now)
+	l.lockout.sweep(now)
+}
+
+// Middleware limits every request per client IP. A rejected request gets
+// status 429 with a Retry-After header and a GraphQL shaped error body.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.config.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		ok, wait := l.requests.take(ip, time.Now())
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limitErr := &Error{RetryAfter: wait}
+		w.Header().Set("Retry-After", strconv.Itoa(limitErr.Seconds()))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]any{
+			"errors": []map[string]any{{
+				"message":    limitErr.Error(),
+				"extensions": limitErr.Extensions(),
+			}},
+		})
+	})
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterLocksAccountUntilSuccess(t *testing.T) {
+	l := NewLimiter(Config{
+		Enabled:          true,
+		OperationIP:      Rule{Burst: 100, Period: time.Minute},
+		OperationAccount: Rule{Burst: 100, Period: time.Minute},
+		LockoutThreshold: 2,
+		LockoutBase:      time.Minute,
+		LockoutMax:       time.Hour,
+	})
+
+	for range 2 {
+		if err := l.Allow("login", "192.0.2.1", "boss"); err != nil {
+			t.Fatal(err)
+		}
+		l.Failed("login", "192.0.2.1", "boss")
+	}
+	var limitErr *Error
+	if err := l.Allow("login", "198.51.100.7", "boss"); !errors.As(err, &limitErr) {
+		t.Fatalf("got %v, want the account locked from another address", err)
+	}
+	if err := l.Allow("login", "192.0.2.1", "other"); err != nil {
+		t.Errorf("other account: %v", err)
+	}
+
+	l.Succeeded("login", "192.0.2.1", "boss")
+	if err := l.Allow("login", "192.0.2.1", "boss"); err != nil {
+		t.Errorf("after a successful login: %v", err)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := NewLimiter(Config{OperationIP: Rule{Burst: 1, Period: time.Hour}, LockoutThreshold: 1, LockoutBase: time.Hour, LockoutMax: time.Hour})
+	for range 3 {
+		l.Failed("login", "192.0.2.1", "")
+		if err := l.Allow("login", "192.0.2.1", ""); err != nil {
+			t.Fatalf("disabled limiter: %v", err)
+		}
+	}
+}
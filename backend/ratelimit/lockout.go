@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// lockout counts failed attempts per key and locks the key once the
+// threshold is reached, for longer with every further failure.
+type lockout struct {
+	mu        sync.Mutex
+	threshold int
+	base      time.Duration
+	max       time.Duration
+	m         map[string]*failures
+}
+
+func newLockout(threshold int, base, max time.Duration) *lockout {
+	return &lockout{threshold: threshold, base: base, max: max, m: map[string]*failures{}}
+}
+
+// check returns how long key is still locked.
+func (l *lockout) check(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.m[key]
+	if !ok || !now.Before(f.lockedUntil) {
+		return 0
+	}
+	return f.lockedUntil.Sub(now)
+}
+
+func (l *lockout) fail(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.m[key]
+	if !ok || now.Sub(f.last) > l.max {
+		f = &failures{}
+		l.m[key] = f
+	}
+	f.count++
+	f.last = now
+	if f.count < l.threshold {
+		return
+	}
+	duration := l.base
+	for i := l.threshold; i < f.count && duration < l.max; i++ {
+		duration *= 2
+	}
+	if duration > l.max {
+		duration = l.max
+	}
+	f.lockedUntil = now.Add(duration)
+}
+
+func (l *lockout) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.m, key)
+}
+
+// sweep forgets keys whose last failure is older than the longest lockout.
+func (l *lockout) sweep(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, f := range l.m {
+		if now.Sub(f.last) > l.max && !now.Before(f.lockedUntil) {
+			delete(l.m, key)
+		}
+	}
+}
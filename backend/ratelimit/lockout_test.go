@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutSchedule(t *testing.T) {
+	l := newLockout(3, time.Minute, 10*time.Minute)
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	// Every failure comes once the previous lock has run out.
+	want := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for i, locked := range want {
+		if wait := l.check("k", now); wait != 0 {
+			t.Fatalf("failure %d: still locked for %s", i+1, wait)
+		}
+		l.fail("k", now)
+		if got := l.check("k", now); got != locked {
+			t.Errorf("failure %d: locked for %s, want %s", i+1, got, locked)
+		}
+		now = now.Add(locked)
+	}
+}
+
+func TestLockoutForgetsOldFailures(t *testing.T) {
+	l := newLockout(2, time.Minute, 10*time.Minute)
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	l.fail("k", now)
+	now = now.Add(11 * time.Minute)
+	l.fail("k", now)
+	if wait := l.check("k", now); wait != 0 {
+		t.Errorf("locked for %s after failures further apart than the longest lockout", wait)
+	}
+}
+
+func TestLockoutReset(t *testing.T) {
+	l := newLockout(2, time.Minute, 10*time.Minute)
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	l.fail("k", now)
+	l.reset("k")
+	l.fail("k", now)
+	if wait := l.check("k", now); wait != 0 {
+		t.Errorf("locked for %s, but the failures before the reset still counted", wait)
+	}
+}
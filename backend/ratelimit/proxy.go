@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientAddr replaces the remote address of requests that come through a
+// trusted proxy with the client named in X-Forwarded-For, so the limits and
+// the sessions see the client rather than the proxy. It has to run before
+// everything that reads RemoteAddr.
+func (l *Limiter) ClientAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if client, ok := l.forwardedClient(r); ok {
+			_, port, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				port = "0"
+			}
+			r.RemoteAddr = net.JoinHostPort(client.String(), port)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedClient walks X-Forwarded-For from the nearest hop back and returns
+// the first address that is not a trusted proxy. Entries further left were
+// written by the client itself and cannot be trusted.
+func (l *Limiter) forwardedClient(r *http.Request) (netip.Addr, bool) {
+	peer, ok := parseAddr(r.RemoteAddr)
+	if !ok || !l.trusted(peer) {
+		return netip.Addr{}, false
+	}
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client, found := netip.Addr{}, false
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			break
+		}
+		client, found = addr, true
+		if !l.trusted(addr) {
+			break
+		}
+	}
+	return client, found
+}
+
+func (l *Limiter) trusted(addr netip.Addr) bool {
+	for _, proxy := range l.config.TrustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr accepts an address with or without a port.
+func parseAddr(raw string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
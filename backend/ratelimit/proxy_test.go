@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientAddr(t *testing.T) {
+	proxies, err := parseProxies("10.0.0.0/8, 192.0.2.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLimiter(Config{TrustedProxies: proxies})
+
+	cases := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"direct client", "198.51.100.7:4000", nil, "198.51.100.7:4000"},
+		{"untrusted peer with header", "198.51.100.7:4000", []string{"203.0.113.5"}, "198.51.100.7:4000"},
+		{"trusted proxy", "192.0.2.10:4000", []string{"203.0.113.5"}, "203.0.113.5:4000"},
+		{"proxy chain", "10.1.2.3:4000", []string{"203.0.113.5, 10.9.9.9"}, "203.0.113.5:4000"},
+		{"spoofed entry before the client", "10.1.2.3:4000", []string{"1.2.3.4, 203.0.113.5"}, "203.0.113.5:4000"},
+		{"several headers", "10.1.2.3:4000", []string{"1.2.3.4", "203.0.113.5"}, "203.0.113.5:4000"},
+		{"trusted proxy without header", "192.0.2.10:4000", nil, "192.0.2.10:4000"},
+		{"garbage in header", "192.0.2.10:4000", []string{"unknown"}, "192.0.2.10:4000"},
+		{"ipv6 client", "192.0.2.10:4000", []string{"2001:db8::1"}, "[2001:db8::1]:4000"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/query", nil)
+			r.RemoteAddr = tc.remote
+			for _, header := range tc.forwarded {
+				r.Header.Add("X-Forwarded-For", header)
+			}
+			var got string
+			l.ClientAddr(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			})).ServeHTTP(httptest.NewRecorder(), r)
+			if got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseProxies(t *testing.T) {
+	if _, err := parseProxies("10.0.0.0/8,proxy.local"); err == nil {
+		t.Error("host name accepted as proxy")
+	}
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apiKeyPrefix marks our keys so they are recognisable in config files and
+// secret scanners.
+const apiKeyPrefix = "rsv_"
+
+var (
+	errAPIKeyInvalid = errors.New("invalid API key")
+	apiKeySelect     = `SELECT id, name, prefix, scopes, created_by, created_at, last_used_at, revoked_at FROM api_keys`
+)
+
+// APIKey is a credential for another system, e.g. the POS. Scopes lists the
+// GraphQL operations the key may call; nothing else is allowed.
+type APIKey struct {
+	ID         string
+	Name       string
+	Prefix     string
+	Scopes     []string
+	CreatedBy  *string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// APIKeyRepository stores API keys. Only the hash of a key is kept; the key
+// itself is shown once when it is created.
+type APIKeyRepository struct {
+	db     *sql.DB
+	driver database.Driver
+}
+
+func NewAPIKeyRepository(db *sql.DB, driver database.Driver) *APIKeyRepository {
+	return &APIKeyRepository{db: db, driver: driver}
+}
+
+func NewAPIKeyStore() *APIKeyRepository {
+	return NewAPIKeyRepository(database.GetDB(), database.GetDriver())
+}
+
+// Create issues a key for the given operations and returns it with the key.
+func (r *APIKeyRepository) Create(by *User, name string, scopes []string) (*APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("Bitte geben Sie einen Namen für den API-Schlüssel an.")
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("Ein API-Schlüssel braucht mindestens eine erlaubte Operation.")
+	}
+	token, _, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	key := apiKeyPrefix + token
+
+	apiKey := &APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		Scopes:    scopes,
+		CreatedBy: nullableString(by.StaffID),
+		CreatedAt: time.Now(),
+	}
+	query := `INSERT INTO api_keys (id, name, prefix, key_hash, scopes, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.db.Exec(r.driver.Rebind(query), apiKey.ID, apiKey.Name, apiKey.Prefix, hashToken(key), strings.Join(scopes, ","), apiKey.CreatedBy, apiKey.CreatedAt)
+	if err != nil {
+		return nil, "", err
+	}
+	return apiKey, key, nil
+}
+
+// Authenticate returns the active key matching key.
+func (r *APIKeyRepository) Authenticate(key string) (*APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, errAPIKeyInvalid
+	}
+	row := r.db.QueryRow(r.driver.Rebind(apiKeySelect+` WHERE key_hash = ?`), hashToken(key))
+	apiKey, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.RevokedAt != nil {
+		return nil, errAPIKeyInvalid
+	}
+	return apiKey, nil
+}
+
+func (r *APIKeyRepository) List() ([]*APIKey, error) {
+	rows, err := r.db.Query(apiKeySelect + ` ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		apiKey, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, apiKey)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepository) Revoke(id string) (*APIKey, error) {
+	now := time.Now()
+	result, err := r.db.Exec(r.driver.Rebind(`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`), now, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return nil, fmt.Errorf("API-Schlüssel nicht gefunden oder bereits widerrufen")
+	}
+	row := r.db.QueryRow(r.driver.Rebind(apiKeySelect+` WHERE id = ?`), id)
+	return scanAPIKey(row)
+}
+
+// RecordUsage counts a call of operation made with a key.
+func (r *APIKeyRepository) RecordUsage(id, operation string) error {
+	now := time.Now()
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO api_key_usage (api_key_id, operation, count, last_used_at) VALUES (?, ?, 1, ?)
+		ON CONFLICT (api_key_id, operation) DO UPDATE SET count = api_key_usage.count + 1, last_used_at = excluded.last_used_at`
+	if _, err := tx.Exec(r.driver.Rebind(query), id, operation, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(r.driver.Rebind(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`), now, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Usage returns the recorded calls of every key by key ID.
+func (r *APIKeyRepository) Usage() (map[string][]*model.APIKeyUsage, error) {
+	rows, err := r.db.Query(`SELECT api_key_id, operation, count, last_used_at FROM api_key_usage ORDER BY operation`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := map[string][]*model.APIKeyUsage{}
+	for rows.Next() {
+		var id string
+		var u model.APIKeyUsage
+		if err := rows.Scan(&id, &u.Operation, &u.Count, &u.LastUsedAt); err != nil {
+			return nil, err
+		}
+		usage[id] = append(usage[id], &u)
+	}
+	return usage, rows.Err()
+}
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*APIKey, error) {
+	var k APIKey
+	var scopes string
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &k.CreatedBy, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	k.Scopes = strings.Split(scopes, ",")
+	return &k, nil
+}
+
+func (k *APIKey) ToModel(usage []*model.APIKeyUsage) *model.APIKey {
+	if usage == nil {
+		usage = []*model.APIKeyUsage{}
+	}
+	return &model.APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedBy:  k.CreatedBy,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		Usage:      usage,
+	}
+}
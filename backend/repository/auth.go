@@ -3,43 +3,219 @@ package repository
 import (
 	"errors"
 	"os"
+	"strings"
 	"time"
 
 	"revervation/backend/graph/model"
+)
+
+const (
+	staffTokenTTL = 16 * time.Hour
+	guestTokenTTL = 15 * time.Minute
+
+	// Sessions end this long after login regardless of refreshes.
+	staffSessionTTL = 30 * 24 * time.Hour
+	guestSessionTTL = 7 * 24 * time.Hour
 
-	"github.com/golang-jwt/jwt/v5"
+	// totpChallengeTTL is how long the second login step may take.
+	totpChallengeTTL = 5 * time.Minute
 )
 
+// accessTokenTypes are the token types accepted on API requests.
+var accessTokenTypes = []TokenType{TokenTypeStaff, TokenTypeGuest, TokenTypeGuestEmail}
+
 type AuthService struct {
-	secret []byte
-	repo   *ReservationRepository
+	tokens   *TokenIssuer
+	store    ReservationStore
+	staff    *StaffRepository
+	sessions *SessionRepository
+	links    *GuestLinkRepository
+	totp     *TwoFactorRepository
+	apiKeys  *APIKeyRepository
+	// totpIssuer names the account in authenticator apps.
+	totpIssuer string
+}
+
+func NewAuthService(store ReservationStore, staff *StaffRepository, sessions *SessionRepository, links *GuestLinkRepository, totp *TwoFactorRepository, apiKeys *APIKeyRepository) *AuthService {
+	keys, err := KeyRingFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	tokens := NewTokenIssuer(keys, os.Getenv("JWT_ISSUER"), os.Getenv("JWT_AUDIENCE"))
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "Yoake Reservierungen"
+	}
+	return &AuthService{tokens: tokens, store: store, staff: staff, sessions: sessions, links: links, totp: totp, apiKeys: apiKeys, totpIssuer: totpIssuer}
+}
+
+// Login checks a staff password. Accounts with two-factor authentication,
+// or all accounts when it is required, get a challenge to complete with
+// VerifyTOTPLogin instead of a session.
+func (a *AuthService) Login(username, password string, client ClientInfo) (*model.LoginResult, error) {
+	staff, err := a.staff.Authenticate(username, password)
+	if err != nil {
+		return nil, err
+	}
+	return a.staffLogin(staff, client)
+}
+
+// AcceptStaffInvite sets the password of an invited or reset account and logs it in.
+func (a *AuthService) AcceptStaffInvite(token, password string, client ClientInfo) (*model.LoginResult, error) {
+	staff, err := a.staff.AcceptInvite(token, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.sessions.RevokeSubject(TokenTypeStaff, staff.ID); err != nil {
+		return nil, err
+	}
+	return a.staffLogin(staff, client)
 }
 
-func NewAuthService() *AuthService {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		panic("No JWT_SECRET")
+func (a *AuthService) staffLogin(staff *StaffUser, client ClientInfo) (*model.LoginResult, error) {
+	enabled, err := a.totp.Enabled(staff.ID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := a.totp.Required()
+	if err != nil {
+		return nil, err
+	}
+	if !enabled && !required {
+		payload, err := a.startSession(TokenTypeStaff, staff.ID, string(staff.Role), client)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResult{Auth: payload}, nil
+	}
+
+	token, claims, err := a.tokens.Sign(TokenTypeTOTPChallenge, staff.ID, "", "", totpChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	challenge := &model.TotpChallenge{Token: token, ExpiresAt: claims.ExpiresAt.Time}
+	if !enabled {
+		// Required but not set up yet: enrol as part of this login.
+		enrollment, err := a.BeginTOTPEnrollment(staff)
+		if err != nil {
+			return nil, err
+		}
+		challenge.Enrollment = enrollment
 	}
-	return &AuthService{secret: []byte(secret), repo: NewReservationRepository()}
+	return &model.LoginResult{Challenge: challenge}, nil
 }
 
-func (a *AuthService) Login(username, password string) (string, error) {
-	if username != os.Getenv("ADMIN_USERNAME") || password != os.Getenv("ADMIN_PASSWORD") {
-		return "", errors.New("invalid credentials")
+// VerifyTOTPLogin completes a login with an authenticator or recovery code.
+// If the challenge came with an enrollment, the code confirms it and the
+// new recovery codes are returned.
+func (a *AuthService) VerifyTOTPLogin(challenge, code string, client ClientInfo) (*model.TotpLoginResult, error) {
+	claims, err := a.tokens.Verify(challenge, TokenTypeTOTPChallenge)
+	if err != nil {
+		return nil, errors.New("Die Anmeldung ist abgelaufen. Bitte melden Sie sich erneut an.")
+	}
+	staff, err := a.staff.GetByID(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if staff.Disabled {
+		return nil, errInvalidCredentials
 	}
 
-	claims := jwt.MapClaims{
-		"sub": os.Getenv("JWT_SECRET"),
-		"exp": time.Now().Add(16 * time.Hour).Unix(),
-		"iat": time.Now().Unix(),
+	enabled, err := a.totp.Enabled(staff.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := &model.TotpLoginResult{RecoveryCodes: []string{}}
+	if enabled {
+		err = a.totp.Verify(staff.ID, code)
+	} else {
+		result.RecoveryCodes, err = a.totp.ConfirmEnrollment(staff.ID, code)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Auth, err = a.startSession(TokenTypeStaff, staff.ID, string(staff.Role), client)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BeginTOTPEnrollment creates a new authenticator secret for a staff account.
+func (a *AuthService) BeginTOTPEnrollment(staff *StaffUser) (*model.TotpEnrollment, error) {
+	secret, err := a.totp.BeginEnrollment(staff.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TotpEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(a.totpIssuer, staff.Username, secret),
+	}, nil
+}
+
+func (a *AuthService) ConfirmTOTPEnrollment(user *User, code string) ([]string, error) {
+	return a.totp.ConfirmEnrollment(user.StaffID, code)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a
+// current code, so a stolen session alone cannot read new ones.
+func (a *AuthService) RegenerateRecoveryCodes(user *User, code string) ([]string, error) {
+	if err := a.totp.Verify(user.StaffID, code); err != nil {
+		return nil, err
+	}
+	return a.totp.RegenerateRecoveryCodes(user.StaffID)
+}
+
+// DisableTOTP removes the caller's authenticator. This is refused while a
+// second factor is required for all staff.
+func (a *AuthService) DisableTOTP(user *User, code string) error {
+	required, err := a.totp.Required()
+	if err != nil {
+		return err
+	}
+	if required {
+		return errors.New("Die Zwei-Faktor-Authentifizierung ist für alle Mitarbeiter vorgeschrieben.")
+	}
+	if err := a.totp.Verify(user.StaffID, code); err != nil {
+		return err
+	}
+	return a.totp.Disable(user.StaffID)
+}
+
+// ResetStaffTOTP removes the authenticator of another account, e.g. after a
+// lost phone, and logs that account out everywhere.
+func (a *AuthService) ResetStaffTOTP(by *User, id string) (*StaffUser, error) {
+	staff, err := a.staff.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOutranks(by, staff.Role); err != nil {
+		return nil, err
+	}
+	if err := a.totp.Disable(id); err != nil {
+		return nil, err
+	}
+	if err := a.RevokeStaffSessions(id); err != nil {
+		return nil, err
+	}
+	return staff, nil
+}
+
+func (a *AuthService) TOTPEnabled(staffID string) (bool, error) {
+	return a.totp.Enabled(staffID)
+}
+
+func (a *AuthService) TOTPRequired() (bool, error) {
+	return a.totp.Required()
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(a.secret)
+func (a *AuthService) SetTOTPRequired(required bool) error {
+	return a.totp.SetRequired(required)
 }
 
-func (a *AuthService) LoginWithReservation(id string, lastName string) (*model.LoginWithReservationResponse, error) {
-	res, err := a.repo.GetByID(id)
+func (a *AuthService) LoginWithReservation(id string, lastName string, client ClientInfo) (*model.LoginWithReservationResponse, error) {
+	res, err := a.store.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
@@ -48,43 +224,229 @@ func (a *AuthService) LoginWithReservation(id string, lastName string) (*model.L
 		return nil, errors.New("Reservierung nicht gefunden")
 	}
 
-	if res.LastName != lastName {
+	if !strings.EqualFold(strings.TrimSpace(res.LastName), strings.TrimSpace(lastName)) {
 		return nil, errors.New("Ihre Nachname stimmen sich nicht ein!")
 	}
 
-	claims := jwt.MapClaims{
-		"sub": res.ID,
-		"exp": time.Now().Add(15 * time.Minute).Unix(),
-		"iat": time.Now().Unix(),
+	return a.GuestLogin(res, client)
+}
+
+// GuestLogin starts a guest session for a reservation the caller has already
+// proven to hold.
+func (a *AuthService) GuestLogin(reservation *model.Reservation, client ClientInfo) (*model.LoginWithReservationResponse, error) {
+	payload, err := a.startSession(TokenTypeGuest, reservation.ID, "", client)
+	if err != nil {
+		return nil, err
 	}
+	response := model.LoginWithReservationResponse{
+		Token:        payload.Token,
+		RefreshToken: payload.RefreshToken,
+		ExpiresAt:    payload.ExpiresAt,
+		Reservation:  reservation,
+	}
+	return &response, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(a.secret)
+// RequestGuestLoginLink queues a login link mail for an email address with
+// upcoming reservations. Any other address is silently ignored, so the
+// caller can answer the same way in both cases.
+func (a *AuthService) RequestGuestLoginLink(email string) error {
+	if normalizeEmail(email) == "" {
+		return nil
+	}
+	reservations, err := a.store.GetUpcomingByEmail(email)
+	if err != nil || len(reservations) == 0 {
+		return err
+	}
+	return a.links.Request(email, reservations[0].ID)
+}
 
+// RedeemGuestLoginLink exchanges a login link token for a guest session that
+// covers every upcoming reservation of the email address.
+func (a *AuthService) RedeemGuestLoginLink(token string, client ClientInfo) (*model.GuestLoginResponse, error) {
+	email, err := a.links.Redeem(token)
+	if err != nil {
+		return nil, err
+	}
+	reservations, err := a.store.GetUpcomingByEmail(email)
 	if err != nil {
 		return nil, err
 	}
+	payload, err := a.startSession(TokenTypeGuestEmail, email, "", client)
+	if err != nil {
+		return nil, err
+	}
+	return &model.GuestLoginResponse{
+		Token:        payload.Token,
+		RefreshToken: payload.RefreshToken,
+		ExpiresAt:    payload.ExpiresAt,
+		Reservations: reservations,
+	}, nil
+}
 
-	response := model.LoginWithReservationResponse{
-		Token:       tokenString,
-		Reservation: res,
+// Refresh rotates a refresh token and issues a new access token for its
+// session. Staff accounts are re-checked so a disabled account cannot refresh.
+func (a *AuthService) Refresh(refreshToken string, client ClientInfo) (*model.AuthPayload, error) {
+	session, refresh, err := a.sessions.Rotate(refreshToken, client)
+	if err != nil {
+		return nil, err
 	}
-	return &response, nil
+
+	role := ""
+	if session.TokenType == TokenTypeStaff {
+		staff, err := a.staff.GetByID(session.Subject)
+		if err != nil || staff.Disabled {
+			if err := a.sessions.Revoke(session.ID); err != nil {
+				return nil, err
+			}
+			return nil, errRefreshInvalid
+		}
+		role = string(staff.Role)
+	}
+
+	token, expiresAt, err := a.issue(session.TokenType, session.Subject, role, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AuthPayload{Token: token, RefreshToken: refresh, ExpiresAt: expiresAt}, nil
+}
+
+func (a *AuthService) startSession(tokenType TokenType, subject, role string, client ClientInfo) (*model.AuthPayload, error) {
+	ttl := guestSessionTTL
+	if tokenType == TokenTypeStaff {
+		ttl = staffSessionTTL
+	}
+	session, refresh, err := a.sessions.Create(tokenType, subject, client, ttl)
+	if err != nil {
+		return nil, err
+	}
+	token, expiresAt, err := a.issue(tokenType, subject, role, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AuthPayload{Token: token, RefreshToken: refresh, ExpiresAt: expiresAt}, nil
 }
 
-func (a *AuthService) SignToken(id string) (string, error) {
-	claims := jwt.MapClaims{
-		"sub": id,
-		"exp": time.Now().Add(15 * time.Minute).Unix(),
-		"iat": time.Now().Unix(),
+// issue signs an access token for a session and records its jti so that
+// revoking the session invalidates it.
+func (a *AuthService) issue(tokenType TokenType, subject, role, sessionID string) (string, time.Time, error) {
+	ttl := guestTokenTTL
+	if tokenType == TokenTypeStaff {
+		ttl = staffTokenTTL
+	}
+	token, claims, err := a.tokens.Sign(tokenType, subject, role, sessionID, ttl)
+	if err != nil {
+		return "", time.Time{}, err
 	}
+	expiresAt := claims.ExpiresAt.Time
+	if err := a.sessions.RecordAccessToken(sessionID, claims.ID, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ValidateToken verifies an access token and checks that its session is
+// still active.
+func (a *AuthService) ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := a.tokens.Verify(tokenString, accessTokenTypes...)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := a.sessions.CheckAccessToken(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	claims.SessionID = sessionID
+	return claims, nil
+}
+
+// ListSessions returns the caller's own sessions, or every active session for
+// managers and owners.
+func (a *AuthService) ListSessions(user *User) ([]*model.Session, error) {
+	var sessions []*Session
+	var err error
+	if user.HasRole(model.StaffRoleManager) {
+		sessions, err = a.sessions.List(nil, nil)
+	} else {
+		tokenType := TokenTypeStaff
+		sessions, err = a.sessions.List(&tokenType, &user.StaffID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := []*model.Session{}
+	for _, s := range sessions {
+		result = append(result, s.ToModel(user.SessionID))
+	}
+	return result, nil
+}
+
+// RevokeSession ends a session. Staff may always end their own; ending
+// somebody else's needs the right to manage that account.
+func (a *AuthService) RevokeSession(user *User, id string) error {
+	session, err := a.sessions.Get(id)
+	if err != nil {
+		return err
+	}
+	own := session.TokenType == TokenTypeStaff && session.Subject == user.StaffID
+	if !own {
+		role := model.StaffRoleHost
+		if session.TokenType == TokenTypeStaff {
+			staff, err := a.staff.GetByID(session.Subject)
+			if err != nil {
+				return err
+			}
+			role = staff.Role
+		}
+		if err := checkManages(user, role); err != nil {
+			return err
+		}
+	}
+	return a.sessions.Revoke(id)
+}
+
+// RevokeStaffSessions logs a staff member out everywhere.
+func (a *AuthService) RevokeStaffSessions(staffID string) error {
+	return a.sessions.RevokeSubject(TokenTypeStaff, staffID)
+}
+
+// DeleteExpiredSessions removes sessions that can no longer be refreshed.
+func (a *AuthService) DeleteExpiredSessions(now time.Time) (int64, error) {
+	return a.sessions.DeleteExpired(now)
+}
+
+// DeleteExpiredGuestLinks removes login links that can no longer be redeemed.
+func (a *AuthService) DeleteExpiredGuestLinks(now time.Time) (int64, error) {
+	return a.links.DeleteExpired(now)
+}
+
+// CreateAPIKey issues a key allowed to call the given operations. The key is
+// returned only here.
+func (a *AuthService) CreateAPIKey(by *User, name string, scopes []string) (*APIKey, string, error) {
+	return a.apiKeys.Create(by, name, scopes)
+}
+
+// ListAPIKeys returns every key, revoked ones included, with its usage.
+func (a *AuthService) ListAPIKeys() ([]*model.APIKey, error) {
+	keys, err := a.apiKeys.List()
+	if err != nil {
+		return nil, err
+	}
+	usage, err := a.apiKeys.Usage()
+	if err != nil {
+		return nil, err
+	}
+	result := []*model.APIKey{}
+	for _, k := range keys {
+		result = append(result, k.ToModel(usage[k.ID]))
+	}
+	return result, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(a.secret)
+func (a *AuthService) RevokeAPIKey(id string) (*APIKey, error) {
+	return a.apiKeys.Revoke(id)
 }
 
-func (a *AuthService) ValidateToken(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(t *jwt.Token) (any, error) {
-		return a.secret, nil
-	})
+func (a *AuthService) RecordAPIKeyUsage(id, operation string) error {
+	return a.apiKeys.RecordUsage(id, operation)
 }
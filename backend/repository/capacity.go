@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"revervation/backend/graph/model"
+	"time"
+)
+
+// occupyingStatusSQL matches the reservations that take up seats in a slot.
+// Guests that are seated or already finished keep their slot; no-shows free it.
+const occupyingStatusSQL = `status IN ('OPEN', 'CONFIRMED', 'SEATED', 'COMPLETED')`
+
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type CapacityLimit struct {
+	MaxCovers       int32
+	MaxReservations int32
+}
+
+func isOccupying(status model.ReservationStatus) bool {
+	switch status {
+	case model.ReservationStatusOpen, model.ReservationStatusConfirmed, model.ReservationStatusSeated, model.ReservationStatusCompleted:
+		return true
+	}
+	return false
+}
+
+// capacityFor picks the most specific limit for the slot: an exact weekday and
+// time match wins over a time-only rule, which wins over a weekday-only rule,
+// which wins over the default row. No matching row means no limit.
+func (r *ReservationRepository) capacityFor(q querier, slotStart time.Time) (*CapacityLimit, error) {
+	query := `SELECT max_covers, max_reservations FROM capacity_limits
+		WHERE (weekday = ? OR weekday IS NULL) AND (slot_time = ? OR slot_time IS NULL)
+		ORDER BY (slot_time IS NULL), (weekday IS NULL)
+		LIMIT 1`
+	var limit CapacityLimit
+	err := q.QueryRow(r.driver.Rebind(query), int(slotStart.Weekday()), slotStart.Format("15:04")).Scan(&limit.MaxCovers, &limit.MaxReservations)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+// slotUsage sums up the occupied seats in [start, end). Bounds are passed in
+// local time because that is how reserve_at is written.
+func (r *ReservationRepository) slotUsage(q querier, start, end time.Time, excludeID string) (int32, int32, error) {
+	query := `SELECT COALESCE(SUM(amount), 0), COUNT(*) FROM reservations
+		WHERE reserve_at >= ? AND reserve_at < ? AND id <> ? AND ` + occupyingStatusSQL
+	var covers, count int32
+	err := q.QueryRow(r.driver.Rebind(query), start.Local(), end.Local(), excludeID).Scan(&covers, &count)
+	return covers, count, err
+}
+
+// checkCapacity locks the reservations table and verifies that a booking of
+// amount guests still fits into the slot of reserveAt. It must run inside the
+// transaction that writes the reservation.
+func (r *ReservationRepository) checkCapacity(tx *sql.Tx, reserveAt time.Time, amount int32, excludeID string) error {
+	if lock := r.driver.LockTable("reservations"); lock != "" {
+		if _, err := tx.Exec(lock); err != nil {
+			return err
+		}
+	}
+
+	schedule, err := r.hours.load(tx)
+	if err != nil {
+		return err
+	}
+	slot, open := schedule.SlotAt(reserveAt)
+	if !open {
+		return fmt.Errorf("Zu dieser Uhrzeit ist das Restaurant geschlossen.")
+	}
+	closures, err := r.hours.closuresOn(tx, schedule, slot.Start)
+	if err != nil {
+		return err
+	}
+	if closure := closureFor(closures, slot); closure != nil {
+		return fmt.Errorf("Das Restaurant ist am %s geschlossen: %s", slot.Start.Format("02.01.2006"), closure.Reason)
+	}
+	start, end := slot.Start, slot.End
+
+	limit, err := r.capacityFor(tx, start)
+	if err != nil || limit == nil {
+		return err
+	}
+
+	covers, count, err := r.slotUsage(tx, start, end, excludeID)
+	if err != nil {
+		return err
+	}
+
+	if covers+amount > limit.MaxCovers || count+1 > limit.MaxReservations {
+		return &SlotFullError{
+			SlotStart:             start,
+			RemainingCovers:       max(limit.MaxCovers-covers, 0),
+			RemainingReservations: max(limit.MaxReservations-count, 0),
+		}
+	}
+	return nil
+}
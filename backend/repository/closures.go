@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"fmt"
+	"revervation/backend/graph/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dayFormat = "2006-01-02"
+
+// Closure marks the restaurant as closed from StartsOn to EndsOn inclusive,
+// either for whole days or, when ServicePeriod is set, only for that period.
+type Closure struct {
+	ID            int64
+	StartsOn      string
+	EndsOn        string
+	ServicePeriod *string
+	Reason        string
+}
+
+func (c Closure) covers(slot Slot) bool {
+	day := slot.Start.Format(dayFormat)
+	if day < c.StartsOn || day > c.EndsOn {
+		return false
+	}
+	return c.ServicePeriod == nil || *c.ServicePeriod == slot.Period
+}
+
+func closureFor(closures []Closure, slot Slot) *Closure {
+	for i := range closures {
+		if closures[i].covers(slot) {
+			return &closures[i]
+		}
+	}
+	return nil
+}
+
+// closuresOn returns every closure touching the restaurant day that contains date.
+func (r *OpeningHoursRepository) closuresOn(q querier, schedule *Schedule, date time.Time) ([]Closure, error) {
+	day := date.In(schedule.Location).Format(dayFormat)
+	return r.queryClosures(q, `SELECT id, starts_on, ends_on, service_period, reason FROM closures WHERE starts_on <= ? AND ends_on >= ? ORDER BY starts_on`, day, day)
+}
+
+func (r *OpeningHoursRepository) queryClosures(q querier, query string, args ...any) ([]Closure, error) {
+	rows, err := q.Query(r.driver.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var closures []Closure
+	for rows.Next() {
+		var c Closure
+		if err := rows.Scan(&c.ID, &c.StartsOn, &c.EndsOn, &c.ServicePeriod, &c.Reason); err != nil {
+			return nil, err
+		}
+		closures = append(closures, c)
+	}
+	return closures, rows.Err()
+}
+
+func (r *OpeningHoursRepository) ClosuresOn(date time.Time) ([]*model.Closure, error) {
+	schedule, err := r.Load()
+	if err != nil {
+		return nil, err
+	}
+	closures, err := r.closuresOn(r.db, schedule, date)
+	if err != nil {
+		return nil, err
+	}
+	return toModelClosures(schedule, closures)
+}
+
+// ListClosures returns the closures overlapping [from, to]. Open bounds are unrestricted.
+func (r *OpeningHoursRepository) ListClosures(from, to *time.Time) ([]*model.Closure, error) {
+	schedule, err := r.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, starts_on, ends_on, service_period, reason FROM closures WHERE 1=1`
+	args := []any{}
+	if from != nil {
+		query += ` AND ends_on >= ?`
+		args = append(args, from.In(schedule.Location).Format(dayFormat))
+	}
+	if to != nil {
+		query += ` AND starts_on <= ?`
+		args = append(args, to.In(schedule.Location).Format(dayFormat))
+	}
+	query += ` ORDER BY starts_on`
+
+	closures, err := r.queryClosures(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return toModelClosures(schedule, closures)
+}
+
+func (r *OpeningHoursRepository) CreateClosure(input model.NewClosure) (*model.Closure, error) {
+	schedule, err := r.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	startsOn := input.StartsOn.In(schedule.Location).Format(dayFormat)
+	endsOn := input.EndsOn.In(schedule.Location).Format(dayFormat)
+	if endsOn < startsOn {
+		return nil, fmt.Errorf("Das Enddatum darf nicht vor dem Startdatum liegen.")
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("Ein Grund für die Schließung ist erforderlich")
+	}
+	if input.ServicePeriod != nil {
+		known := false
+		for _, p := range schedule.Periods {
+			if p.Name == *input.ServicePeriod {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("Unbekannte Servicezeit %q", *input.ServicePeriod)
+		}
+	}
+
+	closure := Closure{StartsOn: startsOn, EndsOn: endsOn, ServicePeriod: input.ServicePeriod, Reason: reason}
+	query := `INSERT INTO closures (starts_on, ends_on, service_period, reason, created_at) VALUES (?, ?, ?, ?, ?) RETURNING id`
+	err = r.db.QueryRow(r.driver.Rebind(query), closure.StartsOn, closure.EndsOn, closure.ServicePeriod, closure.Reason, time.Now()).Scan(&closure.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toModelClosure(schedule, closure)
+}
+
+func (r *OpeningHoursRepository) DeleteClosure(id string) (bool, error) {
+	result, err := r.db.Exec(r.driver.Rebind(`DELETE FROM closures WHERE id = ?`), id)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func toModelClosures(schedule *Schedule, closures []Closure) ([]*model.Closure, error) {
+	result := []*model.Closure{}
+	for _, c := range closures {
+		closure, err := toModelClosure(schedule, c)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, closure)
+	}
+	return result, nil
+}
+
+func toModelClosure(schedule *Schedule, c Closure) (*model.Closure, error) {
+	startsOn, err := time.ParseInLocation(dayFormat, c.StartsOn, schedule.Location)
+	if err != nil {
+		return nil, err
+	}
+	endsOn, err := time.ParseInLocation(dayFormat, c.EndsOn, schedule.Location)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Closure{
+		ID:            strconv.FormatInt(c.ID, 10),
+		StartsOn:      startsOn,
+		EndsOn:        endsOn,
+		ServicePeriod: c.ServicePeriod,
+		Reason:        c.Reason,
+	}, nil
+}
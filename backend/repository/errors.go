@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"fmt"
+	"revervation/backend/graph/model"
+	"time"
+)
+
+// SlotFullError is returned when a booking does not fit into its time slot.
+type SlotFullError struct {
+	SlotStart             time.Time
+	RemainingCovers       int32
+	RemainingReservations int32
+}
+
+func (e *SlotFullError) Error() string {
+	if e.RemainingReservations > 0 && e.RemainingCovers > 0 {
+		return fmt.Sprintf("Um %s Uhr sind nur noch %d Plätze frei.", e.SlotStart.Format("15:04"), e.RemainingCovers)
+	}
+	return fmt.Sprintf("Um %s Uhr sind leider keine Plätze mehr frei.", e.SlotStart.Format("15:04"))
+}
+
+// InvalidTransitionError is returned when the state machine has no edge from From to To.
+type InvalidTransitionError struct {
+	From model.ReservationStatus
+	To   model.ReservationStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("Eine Reservierung mit Status %s kann nicht auf %s gesetzt werden.", e.From, e.To)
+}
+
+// TransitionForbiddenError is returned when the edge exists but the actor may not use it.
+type TransitionForbiddenError struct {
+	From model.ReservationStatus
+	To   model.ReservationStatus
+	Role ActorRole
+}
+
+func (e *TransitionForbiddenError) Error() string {
+	return fmt.Sprintf("Sie dürfen diese Reservierung nicht von %s auf %s setzen.", e.From, e.To)
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"encoding/json"
+	"revervation/backend/graph/model"
+	"strconv"
+	"time"
+)
+
+// FieldChange is one entry of an event's before/after diff. Values are stored
+// in their display form so the history survives schema changes.
+type FieldChange struct {
+	Field  string  `json:"field"`
+	Before *string `json:"before"`
+	After  *string `json:"after"`
+}
+
+// personalFields are the diff entries scrubbed when a reservation is anonymized.
+var personalFields = map[string]bool{
+	"firstName":   true,
+	"lastName":    true,
+	"phoneNumber": true,
+	"email":       true,
+	"notes":       true,
+}
+
+// diffReservations lists every field that differs between before and after.
+// A nil before yields the full initial state.
+func diffReservations(before, after *model.Reservation) []FieldChange {
+	fields := func(r *model.Reservation) map[string]*string {
+		if r == nil {
+			return map[string]*string{}
+		}
+		amount := strconv.Itoa(int(r.Amount))
+		reserveAt := r.ReserveAt.Format(time.RFC3339)
+		status := string(r.Status)
+		return map[string]*string{
+			"firstName":   r.FirstName,
+			"lastName":    &r.LastName,
+			"phoneNumber": &r.PhoneNumber,
+			"email":       &r.Email,
+			"amount":      &amount,
+			"reserveAt":   &reserveAt,
+			"status":      &status,
+			"notes":       r.Notes,
+		}
+	}
+	old, current := fields(before), fields(after)
+
+	var changes []FieldChange
+	for _, name := range []string{"firstName", "lastName", "phoneNumber", "email", "amount", "reserveAt", "status", "notes"} {
+		if equalValue(old[name], current[name]) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, Before: old[name], After: current[name]})
+	}
+	return changes
+}
+
+func equalValue(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// recordEvent appends to the audit trail. It must run inside the transaction
+// of the change it describes.
+func (r *ReservationRepository) recordEvent(q querier, id string, eventType model.ReservationEventType, actor Actor, changes []FieldChange, reason *string) error {
+	if changes == nil {
+		changes = []FieldChange{}
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO reservation_events (reservation_id, event_type, actor_role, actor_id, changes, reason, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = q.Exec(r.driver.Rebind(query), id, eventType, actor.Role, actor.ID, string(encoded), reason, time.Now())
+	return err
+}
+
+// History returns the audit trail of a reservation, oldest event first.
+func (r *ReservationRepository) History(id string) ([]*model.ReservationEvent, error) {
+	query := `SELECT id, event_type, actor_role, actor_id, changes, reason, created_at FROM reservation_events WHERE reservation_id = ? ORDER BY created_at, id`
+	rows, err := r.db.Query(r.driver.Rebind(query), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*model.ReservationEvent{}
+	for rows.Next() {
+		var eventID int64
+		var eventType, encoded string
+		event := &model.ReservationEvent{}
+		if err := rows.Scan(&eventID, &eventType, &event.ActorRole, &event.ActorID, &encoded, &event.Reason, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		var changes []FieldChange
+		if err := json.Unmarshal([]byte(encoded), &changes); err != nil {
+			return nil, err
+		}
+		event.ID = strconv.FormatInt(eventID, 10)
+		event.Type = model.ReservationEventType(eventType)
+		event.Changes = []*model.ReservationChange{}
+		for _, c := range changes {
+			event.Changes = append(event.Changes, &model.ReservationChange{Field: c.Field, Before: c.Before, After: c.After})
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// redactEvents drops the personal values from the diffs of the given
+// reservations' events, keeping who changed what and when.
+func redactEvents(q querier, rebind func(string) string, reservationIDs []string) error {
+	for _, id := range reservationIDs {
+		rows, err := q.Query(rebind(`SELECT id, changes FROM reservation_events WHERE reservation_id = ?`), id)
+		if err != nil {
+			return err
+		}
+		redacted := map[int64]string{}
+		for rows.Next() {
+			var eventID int64
+			var encoded string
+			if err := rows.Scan(&eventID, &encoded); err != nil {
+				rows.Close()
+				return err
+			}
+			var changes []FieldChange
+			if err := json.Unmarshal([]byte(encoded), &changes); err != nil {
+				rows.Close()
+				return err
+			}
+			for i := range changes {
+				if personalFields[changes[i].Field] {
+					changes[i].Before, changes[i].After = nil, nil
+				}
+			}
+			out, err := json.Marshal(changes)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			redacted[eventID] = string(out)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for eventID, encoded := range redacted {
+			if _, err := q.Exec(rebind(`UPDATE reservation_events SET changes = ? WHERE id = ?`), encoded, eventID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"strings"
+	"time"
+)
+
+// guestLinkValidity is how long a login link sent by email can be used.
+const guestLinkValidity = 15 * time.Minute
+
+var errGuestLinkInvalid = errors.New("Der Anmeldelink ist ungültig oder abgelaufen. Bitte fordern Sie einen neuen an.")
+
+// GuestLinkRepository stores the single-use login links mailed to guests.
+// Only the hash of a link token is kept.
+type GuestLinkRepository struct {
+	db     *sql.DB
+	driver database.Driver
+}
+
+func NewGuestLinkRepository(db *sql.DB, driver database.Driver) *GuestLinkRepository {
+	return &GuestLinkRepository{db: db, driver: driver}
+}
+
+func NewGuestLinkStore() *GuestLinkRepository {
+	return NewGuestLinkRepository(database.GetDB(), database.GetDriver())
+}
+
+// Create issues a link token for an email address.
+func (r *GuestLinkRepository) Create(email string) (string, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	query := `INSERT INTO guest_login_links (token_hash, email, created_at, expires_at) VALUES (?, ?, ?, ?)`
+	_, err = r.db.Exec(r.driver.Rebind(query), tokenHash, normalizeEmail(email), now, now.Add(guestLinkValidity))
+	return token, err
+}
+
+// Request queues the mail with a login link for an email address. The
+// message refers to reservationID, the guest's next reservation.
+func (r *GuestLinkRepository) Request(email, reservationID string) error {
+	return enqueueMessage(r.db, r.driver, reservationID, model.ReservationEventBroadcastGuestLogin, model.NotificationChannelEmail, normalizeEmail(email), nil)
+}
+
+// Redeem marks a link token as used and returns the email it was issued for.
+// A token works once and only until it expires.
+func (r *GuestLinkRepository) Redeem(token string) (string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var email string
+	var expiresAt time.Time
+	var usedAt *time.Time
+	tokenHash := hashToken(token)
+	query := `SELECT email, expires_at, used_at FROM guest_login_links WHERE token_hash = ?`
+	err = tx.QueryRow(r.driver.Rebind(query), tokenHash).Scan(&email, &expiresAt, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errGuestLinkInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if usedAt != nil || now.After(expiresAt) {
+		return "", errGuestLinkInvalid
+	}
+
+	result, err := tx.Exec(r.driver.Rebind(`UPDATE guest_login_links SET used_at = ? WHERE token_hash = ? AND used_at IS NULL`), now, tokenHash)
+	if err != nil {
+		return "", err
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 1 {
+		return "", errGuestLinkInvalid
+	}
+	return email, tx.Commit()
+}
+
+// DeleteExpired removes links that can no longer be redeemed.
+func (r *GuestLinkRepository) DeleteExpired(now time.Time) (int64, error) {
+	result, err := r.db.Exec(r.driver.Rebind(`DELETE FROM guest_login_links WHERE expires_at < ?`), now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -6,25 +6,40 @@ import (
 	"regexp"
 	"revervation/backend/database"
 	"revervation/backend/graph/model"
-	"revervation/backend/mailer"
+	"strings"
 	"time"
 )
 
+// ReservationRepository is the SQL implementation of ReservationStore. The
+// driver decides placeholder syntax and operators, so the same type backs
+// both the SQLite and the PostgreSQL store.
 type ReservationRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	driver database.Driver
+	hours  *OpeningHoursRepository
+	// channels are the notification channels the server can send on.
+	channels []model.NotificationChannel
 }
 
-func NewReservationRepository() *ReservationRepository {
-	return &ReservationRepository{db: database.GetDB()}
+func NewSQLiteReservationRepository(db *sql.DB, channels []model.NotificationChannel) *ReservationRepository {
+	return &ReservationRepository{db: db, driver: database.DriverSQLite, hours: NewOpeningHoursRepository(db, database.DriverSQLite), channels: channels}
 }
 
-func (r *ReservationRepository) Create(reservation *model.Reservation) error {
+func NewPostgresReservationRepository(db *sql.DB, channels []model.NotificationChannel) *ReservationRepository {
+	return &ReservationRepository{db: db, driver: database.DriverPostgres, hours: NewOpeningHoursRepository(db, database.DriverPostgres), channels: channels}
+}
+
+// Create saves a new reservation. Without channels the guest is notified by
+// mail.
+func (r *ReservationRepository) Create(actor Actor, reservation *model.Reservation, channels []model.NotificationChannel) error {
 	if reservation.CreatedAt.IsZero() {
 		reservation.CreatedAt = time.Now()
 	}
 	if reservation.ReserveAt.IsZero() {
 		return fmt.Errorf("Reservierungsdatum darf nicht leer sein.")
 	}
+	// reserve_at is written and compared in local time.
+	reservation.ReserveAt = reservation.ReserveAt.Local()
 	if reservation.CreatedAt.After(reservation.ReserveAt) {
 		return fmt.Errorf("Du kannst nicht in die Vergangenheit reservieren.")
 	}
@@ -56,16 +71,62 @@ func (r *ReservationRepository) Create(reservation *model.Reservation) error {
 	if reservation.Amount <= 0 {
 		return fmt.Errorf("Personen Anzahl darf nicht kleiner als 1 sein.")
 	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.checkCapacity(tx, reservation.ReserveAt, reservation.Amount, ""); err != nil {
+		return err
+	}
+
 	query := `INSERT INTO reservations (id, first_name, last_name, amount, phone_number, email, created_at, reserve_at, status, notes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err = r.db.Exec(query, reservation.ID, reservation.FirstName, reservation.LastName, reservation.Amount, reservation.PhoneNumber, reservation.Email, reservation.CreatedAt, reservation.ReserveAt, reservation.Status, reservation.Notes)
-	return err
+	_, err = tx.Exec(r.driver.Rebind(query), reservation.ID, reservation.FirstName, reservation.LastName, reservation.Amount, reservation.PhoneNumber, reservation.Email, reservation.CreatedAt, reservation.ReserveAt, reservation.Status, reservation.Notes)
+	if err != nil {
+		return err
+	}
+	if actor.ID == "" {
+		actor.ID = reservation.ID
+	}
+	if err := r.recordEvent(tx, reservation.ID, model.ReservationEventTypeCreated, actor, diffReservations(nil, reservation), nil); err != nil {
+		return err
+	}
+	if channels == nil {
+		channels = defaultNotificationChannels
+	}
+	if err := r.saveNotificationChannels(tx, reservation.ID, channels); err != nil {
+		return err
+	}
+	if err := r.enqueueNotifications(tx, reservation, model.ReservationEventBroadcastCreated, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *ReservationRepository) Update(id string, firstName, lastName *string, amount *int32, reserveAt *time.Time, notes *string, phoneNumber *string, email *string) (*model.Reservation, error) {
-	existing, err := r.GetByID(id)
+// Update edits a reservation without touching its status, except that a guest
+// moving or resizing a confirmed booking sends it back to OPEN for
+// re-confirmation.
+func (r *ReservationRepository) Update(actor Actor, id string, firstName, lastName *string, amount *int32, reserveAt *time.Time, notes *string, phoneNumber *string, email *string) (*model.Reservation, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	existing, err := r.getByID(tx, id)
 	if err != nil {
 		return nil, err
 	}
+	if !isEditable(existing.Status) {
+		return nil, fmt.Errorf("Eine Reservierung mit Status %s kann nicht bearbeitet werden.", existing.Status)
+	}
+	before := *existing
+	if actor.Role == ActorGuest && existing.Status == model.ReservationStatusConfirmed &&
+		((amount != nil && *amount != existing.Amount) || (reserveAt != nil && !reserveAt.Equal(existing.ReserveAt))) {
+		existing.Status = model.ReservationStatusOpen
+	}
 	if firstName != nil {
 		existing.FirstName = firstName
 	}
@@ -76,7 +137,7 @@ func (r *ReservationRepository) Update(id string, firstName, lastName *string, a
 		existing.Amount = *amount
 	}
 	if reserveAt != nil {
-		existing.ReserveAt = *reserveAt
+		existing.ReserveAt = reserveAt.Local()
 	}
 	if notes != nil {
 		existing.Notes = notes
@@ -88,17 +149,39 @@ func (r *ReservationRepository) Update(id string, firstName, lastName *string, a
 		existing.Email = *email
 	}
 
+	if err := r.checkCapacity(tx, existing.ReserveAt, existing.Amount, id); err != nil {
+		return nil, err
+	}
+
 	query := `UPDATE reservations SET first_name = ?, last_name = ?, amount = ?, reserve_at = ?, notes = ?, status = ?, phone_number = ?, email = ? WHERE id = ?`
-	_, err = r.db.Exec(query, existing.FirstName, existing.LastName, existing.Amount, existing.ReserveAt, existing.Notes, model.ReservationStatusOpen, existing.PhoneNumber, existing.Email, id)
+	_, err = tx.Exec(r.driver.Rebind(query), existing.FirstName, existing.LastName, existing.Amount, existing.ReserveAt, existing.Notes, existing.Status, existing.PhoneNumber, existing.Email, id)
 	if err != nil {
 		return nil, err
 	}
+	var reason *string
+	if existing.Status != before.Status {
+		reopened := "Reservierung vom Gast geändert"
+		reason = &reopened
+	}
+	if err := r.recordEvent(tx, id, model.ReservationEventTypeUpdated, actor, diffReservations(&before, existing), reason); err != nil {
+		return nil, err
+	}
+	if err := r.enqueueNotifications(tx, existing, model.ReservationEventBroadcastUpdated, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return r.GetByID(id)
 }
 
 func (r *ReservationRepository) GetByID(id string) (*model.Reservation, error) {
+	return r.getByID(r.db, id)
+}
+
+func (r *ReservationRepository) getByID(q querier, id string) (*model.Reservation, error) {
 	query := `SELECT id, first_name, last_name, amount, phone_number, email, created_at, reserve_at, status, notes FROM reservations WHERE id = ?`
-	row := r.db.QueryRow(query, id)
+	row := q.QueryRow(r.driver.Rebind(query), id)
 	return r.scanReservation(row)
 }
 
@@ -120,11 +203,11 @@ func (r *ReservationRepository) GetByFilter(filter model.ReservationFilter) ([]*
 		args = append(args, *filter.ID)
 	}
 	if filter.FirstName != nil {
-		query += ` AND first_name LIKE ?`
+		query += ` AND first_name ` + r.driver.Like() + ` ?`
 		args = append(args, "%"+*filter.FirstName+"%")
 	}
 	if filter.LastName != nil {
-		query += ` AND last_name LIKE ?`
+		query += ` AND last_name ` + r.driver.Like() + ` ?`
 		args = append(args, "%"+*filter.LastName+"%")
 	}
 	if filter.Status != nil {
@@ -133,14 +216,14 @@ func (r *ReservationRepository) GetByFilter(filter model.ReservationFilter) ([]*
 	}
 	if filter.DateFrom != nil {
 		query += ` AND reserve_at >= ?`
-		args = append(args, *filter.DateFrom)
+		args = append(args, filter.DateFrom.Local())
 	}
 	if filter.DateTo != nil {
 		query += ` AND reserve_at < ?`
-		args = append(args, *filter.DateTo)
+		args = append(args, filter.DateTo.Local())
 	}
 	query += ` ORDER BY reserve_at DESC`
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.Query(r.driver.Rebind(query), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -157,11 +240,87 @@ func (r *ReservationRepository) GetAllByFilter(filter model.ReservationFilter) (
 		args = append(args, *filter.ID)
 	}
 	if filter.FirstName != nil {
-		query += ` AND first_name LIKE ?`
+		query += ` AND first_name ` + r.driver.Like() + ` ?`
+		args = append(args, "%"+*filter.FirstName+"%")
+	}
+	if filter.LastName != nil {
+		query += ` AND last_name ` + r.driver.Like() + ` ?`
+		args = append(args, "%"+*filter.LastName+"%")
+	}
+	if filter.Status != nil {
+		query += ` AND status = ?`
+		args = append(args, *filter.Status)
+	}
+	if filter.Amount != nil {
+		query += ` AND amount >= ?`
+		args = append(args, *filter.Amount)
+	}
+	if filter.DateFrom != nil {
+		query += ` AND reserve_at >= ?`
+		args = append(args, filter.DateFrom.Local())
+	}
+	if filter.DateTo != nil {
+		query += ` AND reserve_at <= ?`
+		args = append(args, filter.DateTo.Local())
+	}
+	if filter.Email != nil {
+		query += ` AND email ` + r.driver.Like() + ` ?`
+		args = append(args, "%"+*filter.Email+"%")
+	}
+	if filter.PhoneNumber != nil {
+		query += ` AND phone_number ` + r.driver.Like() + ` ?`
+		args = append(args, "%"+*filter.PhoneNumber+"%")
+	}
+
+	query += ` ORDER BY reserve_at DESC`
+	rows, err := r.db.Query(r.driver.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return r.scanReservations(rows)
+}
+
+// GetUpcomingByEmail returns the reservations still ahead for an email
+// address, matched case-insensitively, soonest first.
+func (r *ReservationRepository) GetUpcomingByEmail(email string) ([]*model.Reservation, error) {
+	query := `SELECT id, first_name, last_name, amount, phone_number, email, created_at, reserve_at, status, notes FROM reservations
+		WHERE LOWER(email) = LOWER(?) AND reserve_at >= ? ORDER BY reserve_at`
+	rows, err := r.db.Query(r.driver.Rebind(query), strings.TrimSpace(email), time.Now().Local())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return r.scanReservations(rows)
+}
+
+// GetConfirmedBetween returns the confirmed reservations with reserveAt in
+// (from, to].
+func (r *ReservationRepository) GetConfirmedBetween(from, to time.Time) ([]*model.Reservation, error) {
+	query := `SELECT id, first_name, last_name, amount, phone_number, email, created_at, reserve_at, status, notes FROM reservations
+		WHERE status = ? AND reserve_at > ? AND reserve_at <= ? ORDER BY reserve_at`
+	rows, err := r.db.Query(r.driver.Rebind(query), model.ReservationStatusConfirmed, from.Local(), to.Local())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return r.scanReservations(rows)
+}
+
+func (r *ReservationRepository) GetArchivedByFilter(filter model.ReservationFilter) ([]*model.Reservation, error) {
+	query := `SELECT id, first_name, last_name, amount, phone_number, email, created_at, reserve_at, status, notes FROM reservations_archive WHERE 1=1`
+	args := []any{}
+
+	if filter.ID != nil {
+		query += ` AND id = ?`
+		args = append(args, *filter.ID)
+	}
+	if filter.FirstName != nil {
+		query += ` AND first_name ` + r.driver.Like() + ` ?`
 		args = append(args, "%"+*filter.FirstName+"%")
 	}
 	if filter.LastName != nil {
-		query += ` AND last_name LIKE ?`
+		query += ` AND last_name ` + r.driver.Like() + ` ?`
 		args = append(args, "%"+*filter.LastName+"%")
 	}
 	if filter.Status != nil {
@@ -174,40 +333,117 @@ func (r *ReservationRepository) GetAllByFilter(filter model.ReservationFilter) (
 	}
 	if filter.DateFrom != nil {
 		query += ` AND reserve_at >= ?`
-		args = append(args, (*filter.DateFrom))
+		args = append(args, filter.DateFrom.Local())
 	}
 	if filter.DateTo != nil {
 		query += ` AND reserve_at <= ?`
-		args = append(args, (*filter.DateTo))
+		args = append(args, filter.DateTo.Local())
 	}
 	if filter.Email != nil {
-		query += ` AND email LIKE ?`
+		query += ` AND email ` + r.driver.Like() + ` ?`
 		args = append(args, "%"+*filter.Email+"%")
 	}
 	if filter.PhoneNumber != nil {
-		query += ` AND phone_number LIKE ?`
+		query += ` AND phone_number ` + r.driver.Like() + ` ?`
 		args = append(args, "%"+*filter.PhoneNumber+"%")
 	}
 
 	query += ` ORDER BY reserve_at DESC`
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.Query(r.driver.Rebind(query), args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	return r.scanReservations(rows)
 }
-func (r *ReservationRepository) UpdateStatus(id string, status model.ReservationStatus) (*model.Reservation, error) {
+
+// UpdateStatus moves a reservation along the status state machine and records
+// who did it and why.
+func (r *ReservationRepository) UpdateStatus(actor Actor, id string, status model.ReservationStatus, reason *string) (*model.Reservation, error) {
+	return r.updateStatus(actor, id, status, reason, nil)
+}
+
+// DeclineWithMessage declines a reservation and mails the guest message
+// instead of the standard text.
+func (r *ReservationRepository) DeclineWithMessage(actor Actor, id string, reason *string, message string) (*model.Reservation, error) {
+	return r.updateStatus(actor, id, model.ReservationStatusDeclined, reason, &message)
+}
+
+// ConfirmAttendance notes in the history that the guest said they will
+// come. The reservation itself is unchanged.
+func (r *ReservationRepository) ConfirmAttendance(actor Actor, id string) (*model.Reservation, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	existing, err := r.getByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	// The no-op update locks the row, so a concurrent cancel either waits
+	// for this event or makes the status check fail.
+	result, err := tx.Exec(r.driver.Rebind(`UPDATE reservations SET status = status WHERE id = ? AND status = ?`), id, model.ReservationStatusConfirmed)
+	if err != nil {
+		return nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 || existing.Status != model.ReservationStatusConfirmed {
+		return nil, fmt.Errorf("Nur bestätigte Reservierungen können zugesagt werden.")
+	}
+	if err := r.recordEvent(tx, id, model.ReservationEventTypeAttendanceConfirmed, actor, nil, nil); err != nil {
+		return nil, err
+	}
+	return existing, tx.Commit()
+}
+
+func (r *ReservationRepository) updateStatus(actor Actor, id string, status model.ReservationStatus, reason *string, message *string) (*model.Reservation, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	existing, err := r.getByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTransition(existing.Status, status, actor); err != nil {
+		return nil, err
+	}
+	if !isOccupying(existing.Status) && isOccupying(status) {
+		if err := r.checkCapacity(tx, existing.ReserveAt, existing.Amount, id); err != nil {
+			return nil, err
+		}
+	}
+
 	query := `UPDATE reservations SET status = ? WHERE id = ?`
-	_, err := r.db.Exec(query, status, id)
+	_, err = tx.Exec(r.driver.Rebind(query), status, id)
 	if err != nil {
 		return nil, err
 	}
+	changes := []FieldChange{{Field: "status", Before: (*string)(&existing.Status), After: (*string)(&status)}}
+	if err := r.recordEvent(tx, id, model.ReservationEventTypeStatusChanged, actor, changes, reason); err != nil {
+		return nil, err
+	}
+	if event, ok := guestMailEvent(status); ok {
+		if err := r.enqueueNotifications(tx, existing, event, message); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return r.GetByID(id)
 }
 
 func (r *ReservationRepository) GetStats(date *time.Time) (*model.ReservationInfo, error) {
 	var totalReservation, totalPerson, totalBigReservation, totalOpen, totalConfirmed, totalCanceled int32
+	var totalSeated, totalCompleted, totalNoShow, seatedCovers int32
 
 	// Overall totals query including big reservations
 	query := `SELECT 
@@ -216,43 +452,57 @@ func (r *ReservationRepository) GetStats(date *time.Time) (*model.ReservationInf
 		COALESCE(SUM(CASE WHEN amount >= 5 THEN 1 ELSE 0 END), 0),
 		COALESCE(SUM(CASE WHEN status = 'OPEN' THEN 1 ELSE 0 END), 0),
 		COALESCE(SUM(CASE WHEN status = 'CONFIRMED' THEN 1 ELSE 0 END), 0),
-		COALESCE(SUM(CASE WHEN status = 'CANCELED' THEN 1 ELSE 0 END), 0)
+		COALESCE(SUM(CASE WHEN status = 'CANCELED' THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status = 'SEATED' THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status = 'COMPLETED' THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status = 'NO_SHOW' THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status IN ('SEATED', 'COMPLETED') THEN amount ELSE 0 END), 0)
 		FROM reservations`
+	schedule, err := r.hours.Load()
+	if err != nil {
+		return nil, err
+	}
+
 	args := []any{}
 	if date != nil {
-		loc, _ := time.LoadLocation("Europe/Berlin") // adjust to your local timezone
-		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
-		endOfDay := startOfDay.Add(24 * time.Hour)
+		startOfDay, endOfDay := schedule.DayBounds(*date)
 		query += " WHERE reserve_at >= ? AND reserve_at < ?"
-		args = append(args, startOfDay, endOfDay) // convert to UTC for DB
+		args = append(args, startOfDay.Local(), endOfDay.Local())
 	}
-	err := r.db.QueryRow(query, args...).Scan(&totalReservation, &totalPerson, &totalBigReservation, &totalOpen, &totalConfirmed, &totalCanceled)
+	err = r.db.QueryRow(r.driver.Rebind(query), args...).Scan(&totalReservation, &totalPerson, &totalBigReservation, &totalOpen, &totalConfirmed, &totalCanceled,
+		&totalSeated, &totalCompleted, &totalNoShow, &seatedCovers)
 	if err != nil {
 		return nil, err
 	}
 
-	// By 30-minute intervals from 17:00 to 22:30 local time
+	// By slot of the day's service periods
 	byHours := []*model.ReservationInfoByHour{}
-	loc, _ := time.LoadLocation("Europe/Berlin")
 	if date == nil {
-		currentDate := time.Now().In(loc)
+		currentDate := time.Now()
 		date = &currentDate
 	}
 
-	startLocal := time.Date(date.Year(), date.Month(), date.Day(), 17, 0, 0, 0, loc)
-	endLocal := time.Date(date.Year(), date.Month(), date.Day(), 22, 30, 0, 0, loc)
+	closures, err := r.hours.closuresOn(r.db, schedule, *date)
+	if err != nil {
+		return nil, err
+	}
+	modelClosures, err := toModelClosures(schedule, closures)
+	if err != nil {
+		return nil, err
+	}
 
-	for current := startLocal; current.Before(endLocal); current = current.Add(30 * time.Minute) {
-		next := current.Add(30 * time.Minute)
+	for _, slot := range schedule.DaySlots(*date) {
+		current, next := slot.Start, slot.End
 
-		var hourTotal, hourPerson, hourBig int32
+		var hourTotal, hourPerson, hourBig, hourSeated int32
 		hourQuery := `SELECT 
 				COUNT(*),
 				COALESCE(SUM(amount),0),
-				COALESCE(SUM(CASE WHEN amount >= 5 THEN 1 ELSE 0 END),0)
+				COALESCE(SUM(CASE WHEN amount >= 5 THEN 1 ELSE 0 END),0),
+				COALESCE(SUM(CASE WHEN status = 'SEATED' THEN amount ELSE 0 END),0)
 			FROM reservations
-			WHERE reserve_at >= ? AND reserve_at < ? AND status = ?`
-		err := r.db.QueryRow(hourQuery, current, next, "CONFIRMED").Scan(&hourTotal, &hourPerson, &hourBig)
+			WHERE reserve_at >= ? AND reserve_at < ? AND status IN ('CONFIRMED', 'SEATED', 'COMPLETED')`
+		err := r.db.QueryRow(r.driver.Rebind(hourQuery), current.Local(), next.Local()).Scan(&hourTotal, &hourPerson, &hourBig, &hourSeated)
 		if err != nil {
 			return nil, err
 		}
@@ -261,6 +511,7 @@ func (r *ReservationRepository) GetStats(date *time.Time) (*model.ReservationInf
 			TotalReservation:    hourTotal,
 			TotalPerson:         hourPerson,
 			TotalBigReservation: hourBig,
+			TotalSeatedPerson:   hourSeated,
 			StartsAt:            current,
 			EndsAt:              next,
 		})
@@ -273,10 +524,73 @@ func (r *ReservationRepository) GetStats(date *time.Time) (*model.ReservationInf
 		TotalOpenReservation:      totalOpen,
 		TotalConfirmedReservation: totalConfirmed,
 		TotalCanceledReservation:  totalCanceled,
+		TotalSeatedReservation:    totalSeated,
+		TotalCompletedReservation: totalCompleted,
+		TotalNoShowReservation:    totalNoShow,
+		NoShowRate:                noShowRate(totalSeated+totalCompleted, totalNoShow),
+		SeatedCovers:              seatedCovers,
 		ByHours:                   byHours,
+		Closures:                  modelClosures,
 	}, nil
 }
 
+// noShowRate is the share of no-shows among the reservations whose outcome is
+// known, i.e. that were either seated or never turned up.
+func noShowRate(arrived, noShow int32) float64 {
+	if arrived+noShow == 0 {
+		return 0
+	}
+	return float64(noShow) / float64(arrived+noShow)
+}
+
+func (r *ReservationRepository) GetAvailability(date time.Time, partySize int32) ([]*model.AvailableSlot, error) {
+	if partySize <= 0 {
+		return nil, fmt.Errorf("Personen Anzahl darf nicht kleiner als 1 sein.")
+	}
+
+	schedule, err := r.hours.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	closures, err := r.hours.closuresOn(r.db, schedule, date)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	slots := []*model.AvailableSlot{}
+	for _, slot := range schedule.DaySlots(date) {
+		if !slot.Start.After(now) || closureFor(closures, slot) != nil {
+			continue
+		}
+
+		limit, err := r.capacityFor(r.db, slot.Start)
+		if err != nil {
+			return nil, err
+		}
+		available := &model.AvailableSlot{StartsAt: slot.Start, EndsAt: slot.End}
+		if limit == nil {
+			slots = append(slots, available)
+			continue
+		}
+
+		covers, count, err := r.slotUsage(r.db, slot.Start, slot.End, "")
+		if err != nil {
+			return nil, err
+		}
+		remainingCovers := limit.MaxCovers - covers
+		remainingReservations := limit.MaxReservations - count
+		if remainingCovers < partySize || remainingReservations < 1 {
+			continue
+		}
+		available.RemainingCovers = &remainingCovers
+		available.RemainingReservations = &remainingReservations
+		slots = append(slots, available)
+	}
+	return slots, nil
+}
+
 func (r *ReservationRepository) scanReservation(row *sql.Row) (*model.Reservation, error) {
 	var reservation model.Reservation
 	var firstName, notes sql.NullString
@@ -304,22 +618,6 @@ func (r *ReservationRepository) scanReservation(row *sql.Row) (*model.Reservatio
 	return &reservation, nil
 }
 
-func (r *ReservationRepository) SendMessageToReservation(id string, content string, mailer *mailer.Mailer) error {
-	resv, err := r.GetByID(id)
-	if err != nil {
-		return err
-	}
-	if resv == nil || resv.Email == "" {
-		return fmt.Errorf("reservation not found or missing email")
-	}
-
-	if err := mailer.SendCustomHTMLEmail(resv, content); err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func (r *ReservationRepository) scanReservations(rows *sql.Rows) ([]*model.Reservation, error) {
 	var reservations []*model.Reservation
 	for rows.Next() {
@@ -3,7 +3,8 @@ package repository
 import (
 	"context"
 	"net/http"
-	"os"
+	"revervation/backend/graph/model"
+	"slices"
 	"strings"
 )
 
@@ -11,53 +12,134 @@ var userCtxKey = &contextKey{"user"}
 
 type contextKey struct{ name string }
 
+// User is the caller of a request: either a staff member, with StaffID,
+// Username and Role set, or a guest holding the token of ReservationID. A
+// guest who signed in by email has Email set and owns ReservationIDs. A
+// system calling with an API key has APIKeyID and its Scopes set.
 type User struct {
-	ReservationID string
-	IsAdmin       bool
+	ReservationID  string
+	Email          string
+	ReservationIDs []string
+	StaffID        string
+	Username       string
+	Role           model.StaffRole
+	IsAdmin        bool
+	SessionID      string
+	APIKeyID       string
+	Scopes         []string
 }
 
-func Middleware() func(http.Handler) http.Handler {
-	authService := NewAuthService()
+// Actor returns the user as the actor of a reservation change.
+func (u *User) Actor() Actor {
+	if u.IsAdmin {
+		return Actor{Role: ActorAdmin, ID: u.StaffID}
+	}
+	if u.APIKeyID != "" {
+		return Actor{Role: ActorAdmin, ID: "api-key:" + u.APIKeyID}
+	}
+	if u.Email != "" {
+		return Actor{Role: ActorGuest, ID: u.SessionID}
+	}
+	return Actor{Role: ActorGuest, ID: u.ReservationID}
+}
+
+// HasScope reports whether an API key may call operation.
+func (u *User) HasScope(operation string) bool {
+	return slices.Contains(u.Scopes, operation)
+}
+
+// OwnsReservation reports whether a guest may act on reservation id.
+func (u *User) OwnsReservation(id string) bool {
+	if u.Email == "" {
+		return id == u.ReservationID
+	}
+	for _, owned := range u.ReservationIDs {
+		if owned == id {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether a staff user holds role or a higher one.
+func (u *User) HasRole(role model.StaffRole) bool {
+	return u.IsAdmin && roleRank[u.Role] >= roleRank[role]
+}
 
+func Middleware(authService *AuthService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), clientInfoCtxKey, clientFromRequest(r)))
+
+			if key := r.Header.Get("X-Api-Key"); key != "" {
+				apiKey, err := authService.apiKeys.Authenticate(key)
+				if err != nil {
+					http.Error(w, "Invalid API key", http.StatusForbidden)
+					return
+				}
+				user := User{APIKeyID: apiKey.ID, Scopes: apiKey.Scopes}
+				next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), &user)))
+				return
+			}
+
 			cRaw := r.Header.Get("Authorization")
 			if cRaw == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			c := strings.Split(cRaw, " ")[1]
-
-			token, err := authService.ValidateToken(c)
-			if err != nil {
-				http.Error(w, "Invalid cookie", http.StatusForbidden)
+			c, ok := strings.CutPrefix(cRaw, "Bearer ")
+			if !ok {
+				http.Error(w, "Invalid authorization header", http.StatusForbidden)
 				return
 			}
 
-			var user User
-
-			reservationID, err := token.Claims.GetSubject()
+			claims, err := authService.ValidateToken(c)
 			if err != nil {
-				http.Error(w, "Failed to get user's username", http.StatusInternalServerError)
+				http.Error(w, "Invalid cookie", http.StatusForbidden)
 				return
 			}
 
-			if reservationID == os.Getenv("JWT_SECRET") {
-				user.ReservationID = "yoake"
+			user := User{SessionID: claims.SessionID}
+
+			if claims.Type == TokenTypeStaff {
+				staff, err := authService.staff.GetByID(claims.Subject)
+				if err != nil || staff.Disabled {
+					http.Error(w, "Account disabled", http.StatusForbidden)
+					return
+				}
+				user.StaffID = staff.ID
+				user.Username = staff.Username
+				user.Role = staff.Role
 				user.IsAdmin = true
+			} else if claims.Type == TokenTypeGuestEmail {
+				reservations, err := authService.store.GetUpcomingByEmail(claims.Subject)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				user.Email = claims.Subject
+				for _, reservation := range reservations {
+					user.ReservationIDs = append(user.ReservationIDs, reservation.ID)
+				}
 			} else {
-				user.ReservationID = reservationID
+				user.ReservationID = claims.Subject
 				user.IsAdmin = false
 			}
 
-			ctx := context.WithValue(r.Context(), userCtxKey, &user)
+			ctx := WithUser(r.Context(), &user)
 			r = r.WithContext(ctx)
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// WithUser returns ctx with user as the caller, as Middleware does for a
+// request.
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userCtxKey, user)
+}
+
 func ForContext(ctx context.Context) *User {
 	raw, _ := ctx.Value(userCtxKey).(*User)
 	return raw
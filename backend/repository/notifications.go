@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"fmt"
+	"revervation/backend/graph/model"
+	"slices"
+)
+
+// defaultNotificationChannels apply to reservations without preferences,
+// which includes every reservation made before guests could choose.
+var defaultNotificationChannels = []model.NotificationChannel{model.NotificationChannelEmail}
+
+// NotificationChannels returns the channels a guest is told about changes
+// of their reservation on.
+func (r *ReservationRepository) NotificationChannels(id string) ([]model.NotificationChannel, error) {
+	return r.notificationChannels(r.db, id)
+}
+
+func (r *ReservationRepository) notificationChannels(q querier, id string) ([]model.NotificationChannel, error) {
+	rows, err := q.Query(r.driver.Rebind(`SELECT channel, enabled FROM notification_preferences WHERE reservation_id = ?`), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := false
+	channels := []model.NotificationChannel{}
+	for rows.Next() {
+		var channel string
+		var enabled bool
+		if err := rows.Scan(&channel, &enabled); err != nil {
+			return nil, err
+		}
+		found = true
+		if enabled {
+			channels = append(channels, model.NotificationChannel(channel))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return defaultNotificationChannels, nil
+	}
+	slices.Sort(channels)
+	return channels, nil
+}
+
+// SetNotificationChannels replaces the channels of a reservation. An empty
+// list turns notifications off.
+func (r *ReservationRepository) SetNotificationChannels(id string, channels []model.NotificationChannel) (*model.Reservation, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	reservation, err := r.getByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.saveNotificationChannels(tx, id, channels); err != nil {
+		return nil, err
+	}
+	return reservation, tx.Commit()
+}
+
+func (r *ReservationRepository) saveNotificationChannels(q querier, id string, channels []model.NotificationChannel) error {
+	for _, channel := range channels {
+		if !channel.IsValid() {
+			return fmt.Errorf("Unbekannter Benachrichtigungskanal %s", channel)
+		}
+		if !slices.Contains(r.channels, channel) {
+			return fmt.Errorf("Benachrichtigungen per %s sind nicht verfügbar", channel)
+		}
+	}
+	if _, err := q.Exec(r.driver.Rebind(`DELETE FROM notification_preferences WHERE reservation_id = ?`), id); err != nil {
+		return err
+	}
+	for _, channel := range model.AllNotificationChannel {
+		query := `INSERT INTO notification_preferences (reservation_id, channel, enabled) VALUES (?, ?, ?)`
+		if _, err := q.Exec(r.driver.Rebind(query), id, channel, slices.Contains(channels, channel)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueNotifications queues a message to the guest on each of their
+// channels. Channels the server no longer sends on, e.g. SMS chosen before it
+// was turned off, are skipped. It must run inside the transaction of the
+// change it reports, so the messages are sent if and only if the change is
+// committed.
+func (r *ReservationRepository) enqueueNotifications(q querier, reservation *model.Reservation, event model.ReservationEventBroadcast, customHTML *string) error {
+	channels, err := r.notificationChannels(q, reservation.ID)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if !slices.Contains(r.channels, channel) {
+			continue
+		}
+		recipient := reservation.Email
+		if channel == model.NotificationChannelSms {
+			recipient = reservation.PhoneNumber
+		}
+		if recipient == "" {
+			continue
+		}
+		if err := enqueueMessage(q, r.driver, reservation.ID, event, channel, recipient, customHTML); err != nil {
+			return err
+		}
+	}
+	return nil
+}
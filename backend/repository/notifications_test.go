@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"testing"
+)
+
+func TestNotificationsWithoutSMS(t *testing.T) {
+	openTestDB(t, database.DriverSQLite)
+	store, err := NewReservationStore([]model.NotificationChannel{model.NotificationChannelEmail})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sms := []model.NotificationChannel{model.NotificationChannelEmail, model.NotificationChannelSms}
+
+	if err := store.Create(Actor{Role: ActorGuest}, newTestReservation(dinnerAt(t, 2), 2), sms); err == nil {
+		t.Error("reservation with SMS notifications was created")
+	}
+	reservation := createTestReservation(t, store, dinnerAt(t, 2), 2)
+	if _, err := store.SetNotificationChannels(reservation.ID, sms); err == nil {
+		t.Error("SMS notifications were turned on")
+	}
+
+	// A guest who chose SMS while it was available only gets mails now.
+	if _, err := database.GetDB().Exec(`UPDATE notification_preferences SET enabled = ? WHERE reservation_id = ?`, true, reservation.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.UpdateStatus(staffActor, reservation.ID, model.ReservationStatusConfirmed, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, `SELECT COUNT(*) FROM email_outbox WHERE reservation_id = ? AND channel = ?`, reservation.ID, model.NotificationChannelSms); n != 0 {
+		t.Errorf("%d text messages queued", n)
+	}
+	if n := countRows(t, `SELECT COUNT(*) FROM email_outbox WHERE reservation_id = ? AND channel = ?`, reservation.ID, model.NotificationChannelEmail); n != 2 {
+		t.Errorf("%d mails queued, want 2", n)
+	}
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type ServicePeriod struct {
+	ID      int64
+	Weekday time.Weekday
+	Name    string
+	// OpensAt and ClosesAt are minutes after midnight in restaurant time.
+	OpensAt  int
+	ClosesAt int
+}
+
+// Schedule is the loaded opening-hours configuration. Every slot computation
+// in the backend goes through it.
+type Schedule struct {
+	Location   *time.Location
+	SlotLength time.Duration
+	Periods    []ServicePeriod
+}
+
+type Slot struct {
+	Start  time.Time
+	End    time.Time
+	Period string
+}
+
+// DayBounds returns midnight to midnight of the day containing date in restaurant time.
+func (s *Schedule) DayBounds(date time.Time) (time.Time, time.Time) {
+	local := date.In(s.Location)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.Location)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// DaySlots returns the slot grid of every service period of the day containing
+// date, in chronological order. Each period's grid starts at its opening time.
+func (s *Schedule) DaySlots(date time.Time) []Slot {
+	local := date.In(s.Location)
+	var slots []Slot
+	for _, period := range s.Periods {
+		if period.Weekday != local.Weekday() {
+			continue
+		}
+		start := s.clock(local, period.OpensAt)
+		end := s.clock(local, period.ClosesAt)
+		for current := start; current.Before(end); current = current.Add(s.SlotLength) {
+			slots = append(slots, Slot{Start: current, End: current.Add(s.SlotLength), Period: period.Name})
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	return slots
+}
+
+// SlotAt returns the slot that contains t, or false when the restaurant is closed at t.
+func (s *Schedule) SlotAt(t time.Time) (Slot, bool) {
+	for _, slot := range s.DaySlots(t) {
+		if !t.Before(slot.Start) && t.Before(slot.End) {
+			return slot, true
+		}
+	}
+	return Slot{}, false
+}
+
+func (s *Schedule) clock(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, s.Location)
+}
+
+type OpeningHoursRepository struct {
+	db     *sql.DB
+	driver database.Driver
+}
+
+func NewOpeningHoursRepository(db *sql.DB, driver database.Driver) *OpeningHoursRepository {
+	return &OpeningHoursRepository{db: db, driver: driver}
+}
+
+func NewOpeningHoursStore() *OpeningHoursRepository {
+	return NewOpeningHoursRepository(database.GetDB(), database.GetDriver())
+}
+
+func (r *OpeningHoursRepository) Load() (*Schedule, error) {
+	return r.load(r.db)
+}
+
+func (r *OpeningHoursRepository) load(q querier) (*Schedule, error) {
+	var timezone string
+	var slotMinutes int
+	err := q.QueryRow(`SELECT timezone, slot_minutes FROM schedule_settings WHERE id = 1`).Scan(&timezone, &slotMinutes)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`SELECT id, weekday, name, opens_at, closes_at FROM service_periods ORDER BY weekday, opens_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedule := &Schedule{Location: loc, SlotLength: time.Duration(slotMinutes) * time.Minute}
+	for rows.Next() {
+		var period ServicePeriod
+		var weekday int
+		var opensAt, closesAt string
+		if err := rows.Scan(&period.ID, &weekday, &period.Name, &opensAt, &closesAt); err != nil {
+			return nil, err
+		}
+		period.Weekday = time.Weekday(weekday)
+		if period.OpensAt, err = parseClock(opensAt); err != nil {
+			return nil, err
+		}
+		if period.ClosesAt, err = parseClock(closesAt); err != nil {
+			return nil, err
+		}
+		schedule.Periods = append(schedule.Periods, period)
+	}
+	return schedule, rows.Err()
+}
+
+// Location returns the timezone of the restaurant.
+func (r *OpeningHoursRepository) Location() (*time.Location, error) {
+	var timezone string
+	if err := r.db.QueryRow(`SELECT timezone FROM schedule_settings WHERE id = 1`).Scan(&timezone); err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(timezone)
+}
+
+func (r *OpeningHoursRepository) Get() (*model.OpeningHours, error) {
+	schedule, err := r.Load()
+	if err != nil {
+		return nil, err
+	}
+	return toOpeningHours(schedule), nil
+}
+
+func (r *OpeningHoursRepository) UpdateSettings(timezone string, slotMinutes int32) (*model.OpeningHours, error) {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("Unbekannte Zeitzone %q", timezone)
+	}
+	if slotMinutes < 5 || slotMinutes > 240 {
+		return nil, fmt.Errorf("Die Slot-Länge muss zwischen 5 und 240 Minuten liegen.")
+	}
+
+	query := `UPDATE schedule_settings SET timezone = ?, slot_minutes = ? WHERE id = 1`
+	if _, err := r.db.Exec(r.driver.Rebind(query), timezone, slotMinutes); err != nil {
+		return nil, err
+	}
+	return r.Get()
+}
+
+// SetServicePeriods replaces all service periods of one weekday.
+func (r *OpeningHoursRepository) SetServicePeriods(weekday model.Weekday, periods []*model.ServicePeriodInput) (*model.OpeningHours, error) {
+	day, ok := weekdayFromModel[weekday]
+	if !ok {
+		return nil, fmt.Errorf("Unbekannter Wochentag %s", weekday)
+	}
+
+	type clockRange struct{ opens, closes int }
+	ranges := make([]clockRange, 0, len(periods))
+	for _, p := range periods {
+		if p.Name == "" {
+			return nil, fmt.Errorf("Name der Servicezeit ist erforderlich")
+		}
+		opens, err := parseClock(p.OpensAt)
+		if err != nil {
+			return nil, err
+		}
+		closes, err := parseClock(p.ClosesAt)
+		if err != nil {
+			return nil, err
+		}
+		if closes <= opens {
+			return nil, fmt.Errorf("%s: Schließzeit muss nach der Öffnungszeit liegen", p.Name)
+		}
+		ranges = append(ranges, clockRange{opens, closes})
+	}
+	for i := range ranges {
+		for j := i + 1; j < len(ranges); j++ {
+			if ranges[i].opens < ranges[j].closes && ranges[j].opens < ranges[i].closes {
+				return nil, fmt.Errorf("Servicezeiten %s und %s überschneiden sich", periods[i].Name, periods[j].Name)
+			}
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(r.driver.Rebind(`DELETE FROM service_periods WHERE weekday = ?`), int(day)); err != nil {
+		return nil, err
+	}
+	query := `INSERT INTO service_periods (weekday, name, opens_at, closes_at) VALUES (?, ?, ?, ?)`
+	for i, p := range periods {
+		if _, err := tx.Exec(r.driver.Rebind(query), int(day), p.Name, formatClock(ranges[i].opens), formatClock(ranges[i].closes)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.Get()
+}
+
+var weekdayFromModel = map[model.Weekday]time.Weekday{
+	model.WeekdayMonday:    time.Monday,
+	model.WeekdayTuesday:   time.Tuesday,
+	model.WeekdayWednesday: time.Wednesday,
+	model.WeekdayThursday:  time.Thursday,
+	model.WeekdayFriday:    time.Friday,
+	model.WeekdaySaturday:  time.Saturday,
+	model.WeekdaySunday:    time.Sunday,
+}
+
+func weekdayToModel(day time.Weekday) model.Weekday {
+	for m, d := range weekdayFromModel {
+		if d == day {
+			return m
+		}
+	}
+	return model.WeekdayMonday
+}
+
+func toOpeningHours(schedule *Schedule) *model.OpeningHours {
+	hours := &model.OpeningHours{
+		Timezone:    schedule.Location.String(),
+		SlotMinutes: int32(schedule.SlotLength / time.Minute),
+		Periods:     []*model.ServicePeriod{},
+	}
+	for _, p := range schedule.Periods {
+		hours.Periods = append(hours.Periods, &model.ServicePeriod{
+			ID:       strconv.FormatInt(p.ID, 10),
+			Weekday:  weekdayToModel(p.Weekday),
+			Name:     p.Name,
+			OpensAt:  formatClock(p.OpensAt),
+			ClosesAt: formatClock(p.ClosesAt),
+		})
+	}
+	return hours
+}
+
+// parseClock turns "HH:MM" into minutes after midnight. "24:00" is allowed as a closing time.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		if value == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("Ungültige Uhrzeit %q, erwartet HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const outboxSelect = `SELECT id, reservation_id, event, channel, recipient, custom_html, status, attempts, last_error, next_attempt_at, created_at, sent_at FROM email_outbox`
+
+// GuestLoginSender mails a guest the link that signs them in.
+type GuestLoginSender interface {
+	SendGuestLoginLink(to string, token string) error
+}
+
+// Notifier delivers the messages queued in the outbox on their channel.
+// customHTML is only set for a message written by staff.
+type Notifier interface {
+	Notify(channel model.NotificationChannel, reservation *model.Reservation, event model.ReservationEventBroadcast, customHTML *string) error
+}
+
+type OutboxMessage struct {
+	ID            string
+	ReservationID string
+	Event         model.ReservationEventBroadcast
+	Channel       model.NotificationChannel
+	Recipient     string
+	CustomHTML    *string
+	Status        model.EmailStatus
+	Attempts      int32
+	LastError     *string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+// guestMailEvent is the mail a guest gets when their reservation moves to
+// status. Changes on the floor (seated, completed, no-show) are not mailed.
+func guestMailEvent(status model.ReservationStatus) (model.ReservationEventBroadcast, bool) {
+	switch status {
+	case model.ReservationStatusOpen:
+		return model.ReservationEventBroadcastCreated, true
+	case model.ReservationStatusConfirmed:
+		return model.ReservationEventBroadcastConfirmed, true
+	case model.ReservationStatusDeclined:
+		return model.ReservationEventBroadcastDeclined, true
+	case model.ReservationStatusCanceled:
+		return model.ReservationEventBroadcastCanceled, true
+	}
+	return "", false
+}
+
+// enqueueMessage queues one message. Messages about a reservation are queued
+// in the transaction of the change they report.
+func enqueueMessage(q querier, driver database.Driver, reservationID string, event model.ReservationEventBroadcast, channel model.NotificationChannel, recipient string, customHTML *string) error {
+	now := time.Now()
+	query := `INSERT INTO email_outbox (id, reservation_id, event, channel, recipient, custom_html, status, attempts, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?, ?)`
+	_, err := q.Exec(driver.Rebind(query), uuid.New().String(), reservationID, event, channel, recipient, customHTML, model.EmailStatusPending, now, now)
+	return err
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration
+	// MaxAttempts is how often delivery is tried before the mail is
+	// dead-lettered as FAILED.
+	MaxAttempts int32
+	// The wait after the n-th failure is BackoffBase * 2^(n-1), at most BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Lease is how long a message being sent is hidden from other workers.
+	// It must stay well above the SMTP and SMS gateway timeouts, or a slow
+	// send is picked up again and delivered twice.
+	Lease time.Duration
+	// SentRetention is how long delivered mails are kept.
+	SentRetention time.Duration
+}
+
+// OutboxConfigFromEnv reads OUTBOX_POLL_INTERVAL, OUTBOX_MAX_ATTEMPTS,
+// OUTBOX_BACKOFF_BASE, OUTBOX_BACKOFF_MAX, OUTBOX_LEASE (default 5m) and
+// OUTBOX_SENT_RETENTION_DAYS.
+func OutboxConfigFromEnv() (OutboxConfig, error) {
+	cfg := OutboxConfig{
+		PollInterval:  5 * time.Second,
+		MaxAttempts:   8,
+		BackoffBase:   30 * time.Second,
+		BackoffMax:    time.Hour,
+		Lease:         5 * time.Minute,
+		SentRetention: 30 * 24 * time.Hour,
+	}
+
+	durations := map[string]*time.Duration{
+		"OUTBOX_POLL_INTERVAL": &cfg.PollInterval,
+		"OUTBOX_BACKOFF_BASE":  &cfg.BackoffBase,
+		"OUTBOX_BACKOFF_MAX":   &cfg.BackoffMax,
+		"OUTBOX_LEASE":         &cfg.Lease,
+	}
+	for name, target := range durations {
+		if raw := os.Getenv(name); raw != "" {
+			d, err := time.ParseDuration(raw)
+			if err != nil || d <= 0 {
+				return cfg, fmt.Errorf("invalid %s %q", name, raw)
+			}
+			*target = d
+		}
+	}
+	if raw := os.Getenv("OUTBOX_MAX_ATTEMPTS"); raw != "" {
+		attempts, err := strconv.Atoi(raw)
+		if err != nil || attempts < 1 {
+			return cfg, fmt.Errorf("invalid OUTBOX_MAX_ATTEMPTS %q", raw)
+		}
+		cfg.MaxAttempts = int32(attempts)
+	}
+	if raw := os.Getenv("OUTBOX_SENT_RETENTION_DAYS"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 1 {
+			return cfg, fmt.Errorf("invalid OUTBOX_SENT_RETENTION_DAYS %q", raw)
+		}
+		cfg.SentRetention = time.Duration(days) * 24 * time.Hour
+	}
+	if cfg.Lease < time.Minute {
+		return cfg, fmt.Errorf("invalid OUTBOX_LEASE %s, must be at least 1m", cfg.Lease)
+	}
+	return cfg, nil
+}
+
+// backoff is the wait before the next delivery attempt after attempts failures.
+func (c OutboxConfig) backoff(attempts int32) time.Duration {
+	wait := c.BackoffBase
+	for i := int32(1); i < attempts && wait < c.BackoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, c.BackoffMax)
+}
+
+// OutboxService delivers queued mails and text messages in the background
+// and lets staff look at and resend the ones that failed for good.
+type OutboxService struct {
+	db     *sql.DB
+	driver database.Driver
+	config OutboxConfig
+	store  ReservationStore
+	sender Notifier
+	links  *GuestLinkRepository
+	logins GuestLoginSender
+}
+
+func NewOutboxService(cfg OutboxConfig, store ReservationStore, sender Notifier, logins GuestLoginSender) *OutboxService {
+	return &OutboxService{db: database.GetDB(), driver: database.GetDriver(), config: cfg, store: store, sender: sender, links: NewGuestLinkStore(), logins: logins}
+}
+
+// Run delivers due mails every PollInterval until ctx is cancelled.
+func (s *OutboxService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.DeliverDue(time.Now()); err != nil {
+			log.Printf("Outbox delivery failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue tries every pending mail whose next attempt is due and returns
+// how many were sent.
+func (s *OutboxService) DeliverDue(now time.Time) (int, error) {
+	rows, err := s.db.Query(s.driver.Rebind(outboxSelect+` WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT 50`), model.EmailStatusPending, now)
+	if err != nil {
+		return 0, err
+	}
+	messages, err := scanOutboxMessages(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, message := range messages {
+		claimed, err := s.claim(message, now)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+		if err := s.deliver(message); err != nil {
+			if err := s.recordFailure(message, err, now); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		query := `UPDATE email_outbox SET status = ?, attempts = attempts + 1, last_error = NULL, sent_at = ? WHERE id = ?`
+		if _, err := s.db.Exec(s.driver.Rebind(query), model.EmailStatusSent, time.Now(), message.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// claim pushes the next attempt of a message out by the lease while it is
+// being sent, so another worker polling the same database skips it.
+func (s *OutboxService) claim(message *OutboxMessage, now time.Time) (bool, error) {
+	query := `UPDATE email_outbox SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?`
+	result, err := s.db.Exec(s.driver.Rebind(query), now.Add(s.config.Lease), message.ID, model.EmailStatusPending, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// errReservationGone fails a message for good: its reservation has been
+// archived since it was queued, and retrying cannot bring it back.
+var errReservationGone = errors.New("Reservierung nicht mehr vorhanden")
+
+func (s *OutboxService) deliver(message *OutboxMessage) error {
+	if message.Event == model.ReservationEventBroadcastGuestLogin {
+		// The link token is only created now, so the outbox never holds a
+		// usable token and the link is valid for its full lifetime.
+		token, err := s.links.Create(message.Recipient)
+		if err != nil {
+			return err
+		}
+		return s.logins.SendGuestLoginLink(message.Recipient, token)
+	}
+	reservation, err := s.store.GetByID(message.ReservationID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && reservation == nil) {
+		return errReservationGone
+	}
+	if err != nil {
+		return err
+	}
+	// The message goes to the address or number the change was made for,
+	// even if the guest has changed it since.
+	if message.Channel == model.NotificationChannelSms {
+		reservation.PhoneNumber = message.Recipient
+	} else {
+		reservation.Email = message.Recipient
+	}
+	return s.sender.Notify(message.Channel, reservation, message.Event, message.CustomHTML)
+}
+
+func (s *OutboxService) recordFailure(message *OutboxMessage, sendErr error, now time.Time) error {
+	attempts := message.Attempts + 1
+	status := model.EmailStatusPending
+	if attempts >= s.config.MaxAttempts || errors.Is(sendErr, errReservationGone) {
+		status = model.EmailStatusFailed
+		log.Printf("Giving up on %s message %s to %s after %d attempts: %v", message.Channel, message.ID, message.Recipient, attempts, sendErr)
+	}
+	query := `UPDATE email_outbox SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?`
+	_, err := s.db.Exec(s.driver.Rebind(query), status, attempts, sendErr.Error(), now.Add(s.config.backoff(attempts)), message.ID)
+	return err
+}
+
+// List returns the queued mails with status, newest first.
+func (s *OutboxService) List(status model.EmailStatus) ([]*model.OutboxEmail, error) {
+	rows, err := s.db.Query(s.driver.Rebind(outboxSelect+` WHERE status = ? ORDER BY created_at DESC`), status)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := scanOutboxMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	result := []*model.OutboxEmail{}
+	for _, message := range messages {
+		result = append(result, message.ToModel())
+	}
+	return result, nil
+}
+
+// Resend queues a failed mail again with a fresh set of attempts.
+func (s *OutboxService) Resend(id string) (*model.OutboxEmail, error) {
+	query := `UPDATE email_outbox SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ? AND status = ?`
+	result, err := s.db.Exec(s.driver.Rebind(query), model.EmailStatusPending, time.Now(), id, model.EmailStatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return nil, fmt.Errorf("Nur fehlgeschlagene E-Mails können erneut gesendet werden.")
+	}
+	row := s.db.QueryRow(s.driver.Rebind(outboxSelect+` WHERE id = ?`), id)
+	message, err := scanOutboxMessage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("E-Mail nicht gefunden")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return message.ToModel(), nil
+}
+
+// DeleteSent removes delivered mails older than SentRetention.
+func (s *OutboxService) DeleteSent(now time.Time) (int64, error) {
+	query := `DELETE FROM email_outbox WHERE status = ? AND sent_at < ?`
+	result, err := s.db.Exec(s.driver.Rebind(query), model.EmailStatusSent, now.Add(-s.config.SentRetention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanOutboxMessage(row interface{ Scan(...any) error }) (*OutboxMessage, error) {
+	var m OutboxMessage
+	var event, channel, status string
+	err := row.Scan(&m.ID, &m.ReservationID, &event, &channel, &m.Recipient, &m.CustomHTML, &status, &m.Attempts, &m.LastError, &m.NextAttemptAt, &m.CreatedAt, &m.SentAt)
+	if err != nil {
+		return nil, err
+	}
+	m.Event = model.ReservationEventBroadcast(event)
+	m.Channel = model.NotificationChannel(channel)
+	m.Status = model.EmailStatus(status)
+	return &m, nil
+}
+
+func scanOutboxMessages(rows *sql.Rows) ([]*OutboxMessage, error) {
+	defer rows.Close()
+	var messages []*OutboxMessage
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (m *OutboxMessage) ToModel() *model.OutboxEmail {
+	return &model.OutboxEmail{
+		ID:            m.ID,
+		ReservationID: m.ReservationID,
+		Event:         m.Event,
+		Channel:       m.Channel,
+		Recipient:     m.Recipient,
+		Status:        m.Status,
+		Attempts:      m.Attempts,
+		LastError:     m.LastError,
+		NextAttemptAt: m.NextAttemptAt,
+		CreatedAt:     m.CreatedAt,
+		SentAt:        m.SentAt,
+	}
+}
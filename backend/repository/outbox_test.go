@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"testing"
+	"time"
+)
+
+// fakeNotifier records the reservations it was asked to notify about.
+type fakeNotifier struct {
+	sent []string
+}
+
+func (n *fakeNotifier) Notify(channel model.NotificationChannel, reservation *model.Reservation, event model.ReservationEventBroadcast, customHTML *string) error {
+	n.sent = append(n.sent, reservation.ID)
+	return nil
+}
+
+func TestOutboxGivesUpOnMissingReservation(t *testing.T) {
+	for _, driver := range testDrivers() {
+		t.Run(string(driver), func(t *testing.T) {
+			store := openTestStore(t, driver)
+			kept := createTestReservation(t, store, dinnerAt(t, 2), 2)
+			gone := createTestReservation(t, store, dinnerAt(t, 3), 2)
+			// Archived after its confirmation was queued.
+			for _, query := range []string{`DELETE FROM notification_preferences WHERE reservation_id = ?`, `DELETE FROM reservations WHERE id = ?`} {
+				if _, err := database.GetDB().Exec(database.GetDriver().Rebind(query), gone.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			notifier := &fakeNotifier{}
+			outbox := NewOutboxService(OutboxConfig{MaxAttempts: 5, BackoffBase: time.Minute, BackoffMax: time.Minute, Lease: time.Minute}, store, notifier, nil)
+			sent, err := outbox.DeliverDue(time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sent != 1 || len(notifier.sent) != 1 || notifier.sent[0] != kept.ID {
+				t.Fatalf("sent %d: %v, want only %s", sent, notifier.sent, kept.ID)
+			}
+			if n := countRows(t, `SELECT COUNT(*) FROM email_outbox WHERE reservation_id = ? AND status = ?`, gone.ID, model.EmailStatusFailed); n != 1 {
+				t.Errorf("%d failed messages for the missing reservation, want 1 after the first attempt", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	reminderSending = "SENDING"
+	reminderSent    = "SENT"
+	reminderSkipped = "SKIPPED"
+)
+
+var errReminderLinkInvalid = errors.New("Der Link ist ungültig oder abgelaufen.")
+
+// ReminderSender mails the reminder of a reservation. The token goes into
+// the confirm and cancel links.
+type ReminderSender interface {
+	SendReminder(reservation *model.Reservation, token string) error
+}
+
+type ReminderConfig struct {
+	// Offsets before reserveAt at which a reminder is sent, largest first.
+	Offsets  []time.Duration
+	Schedule string
+	// Lease is how long a reminder being sent stays claimed. A claim left
+	// behind by a process that died mid-send is taken over after it; a zero
+	// Lease never takes a claim over.
+	Lease time.Duration
+}
+
+// ReminderConfigFromEnv reads REMINDER_OFFSETS (comma separated durations,
+// default "24h,3h", empty to turn reminders off) and REMINDER_SCHEDULE
+// (default every 5 minutes) and REMINDER_LEASE (default 10m).
+func ReminderConfigFromEnv() (ReminderConfig, error) {
+	cfg := ReminderConfig{
+		Offsets:  []time.Duration{24 * time.Hour, 3 * time.Hour},
+		Schedule: "*/5 * * * *",
+		Lease:    10 * time.Minute,
+	}
+	if raw, ok := os.LookupEnv("REMINDER_OFFSETS"); ok {
+		cfg.Offsets = nil
+		for _, part := range strings.Split(raw, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			offset, err := time.ParseDuration(part)
+			if err != nil || offset < time.Minute {
+				return ReminderConfig{}, fmt.Errorf("invalid REMINDER_OFFSETS entry %q", part)
+			}
+			cfg.Offsets = append(cfg.Offsets, offset)
+		}
+	}
+	slices.Sort(cfg.Offsets)
+	slices.Reverse(cfg.Offsets)
+	cfg.Offsets = slices.Compact(cfg.Offsets)
+	if raw := os.Getenv("REMINDER_SCHEDULE"); raw != "" {
+		cfg.Schedule = raw
+	}
+	if raw := os.Getenv("REMINDER_LEASE"); raw != "" {
+		lease, err := time.ParseDuration(raw)
+		if err != nil || lease < time.Minute {
+			return ReminderConfig{}, fmt.Errorf("invalid REMINDER_LEASE %q, must be at least 1m", raw)
+		}
+		cfg.Lease = lease
+	}
+	return cfg, nil
+}
+
+// ReminderService mails confirmed guests before their visit, unless they
+// turned mail notifications off. Every offset of a reservation is recorded
+// before its mail goes out, so a restart never sends a reminder twice.
+type ReminderService struct {
+	db     *sql.DB
+	driver database.Driver
+	config ReminderConfig
+	store  ReservationStore
+	sender ReminderSender
+}
+
+func NewReminderService(cfg ReminderConfig, store ReservationStore, sender ReminderSender) *ReminderService {
+	return &ReminderService{db: database.GetDB(), driver: database.GetDriver(), config: cfg, store: store, sender: sender}
+}
+
+// Run sends the reminders that are due. A guest gets one mail per run even
+// if several offsets are due, e.g. for a reservation made the same day; the
+// larger offsets are skipped.
+func (s *ReminderService) Run(now time.Time) (int, error) {
+	if len(s.config.Offsets) == 0 {
+		return 0, nil
+	}
+	reservations, err := s.store.GetConfirmedBetween(now, now.Add(s.config.Offsets[0]))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, reservation := range reservations {
+		if reservation.Email == "" {
+			continue
+		}
+		channels, err := s.store.NotificationChannels(reservation.ID)
+		if err != nil {
+			return sent, err
+		}
+		if !slices.Contains(channels, model.NotificationChannelEmail) {
+			continue
+		}
+		var due []time.Duration
+		for _, offset := range s.config.Offsets {
+			if reservation.ReserveAt.Sub(now) <= offset {
+				due = append(due, offset)
+			}
+		}
+		token, err := s.claim(reservation, due, now)
+		if err != nil {
+			return sent, err
+		}
+		if token == "" {
+			continue
+		}
+		if err := s.sender.SendReminder(reservation, token); err != nil {
+			log.Printf("Reminder for reservation %s failed: %v", reservation.ID, err)
+			s.release(reservation.ID, due[len(due)-1])
+			continue
+		}
+		query := `UPDATE reservation_reminders SET status = ?, sent_at = ? WHERE reservation_id = ? AND offset_minutes = ?`
+		if _, err := s.db.Exec(s.driver.Rebind(query), reminderSent, time.Now(), reservation.ID, offsetMinutes(due[len(due)-1])); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// claim records the due offsets of a reservation and returns the link token
+// of the reminder to send, or "" if it was sent before or another run is
+// still sending it.
+func (s *ReminderService) claim(reservation *model.Reservation, due []time.Duration, now time.Time) (string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	insert := `INSERT INTO reservation_reminders (reservation_id, offset_minutes, status, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (reservation_id, offset_minutes) DO NOTHING`
+	for _, offset := range due[:len(due)-1] {
+		if _, err := tx.Exec(s.driver.Rebind(insert), reservation.ID, offsetMinutes(offset), reminderSkipped, nil, now, reservation.ReserveAt); err != nil {
+			return "", err
+		}
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	result, err := tx.Exec(s.driver.Rebind(insert), reservation.ID, offsetMinutes(due[len(due)-1]), reminderSending, tokenHash, now, reservation.ReserveAt)
+	if err != nil {
+		return "", err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		if s.config.Lease <= 0 {
+			return "", nil
+		}
+		// Take over a claim whose lease ran out without the mail being sent.
+		reclaim := `UPDATE reservation_reminders SET token_hash = ?, created_at = ? WHERE reservation_id = ? AND offset_minutes = ? AND status = ? AND created_at < ?`
+		result, err = tx.Exec(s.driver.Rebind(reclaim), tokenHash, now, reservation.ID, offsetMinutes(due[len(due)-1]), reminderSending, now.Add(-s.config.Lease))
+		if err != nil {
+			return "", err
+		}
+		if n, err = result.RowsAffected(); err != nil || n == 0 {
+			return "", err
+		}
+	}
+	return token, tx.Commit()
+}
+
+// release forgets a reminder whose mail failed, so the next run retries it.
+func (s *ReminderService) release(reservationID string, offset time.Duration) {
+	query := `DELETE FROM reservation_reminders WHERE reservation_id = ? AND offset_minutes = ? AND status = ?`
+	if _, err := s.db.Exec(s.driver.Rebind(query), reservationID, offsetMinutes(offset), reminderSending); err != nil {
+		log.Printf("Failed to release reminder for reservation %s: %v", reservationID, err)
+	}
+}
+
+// Respond handles a click on the confirm or cancel link of a reminder. The
+// links work until the reservation starts.
+func (s *ReminderService) Respond(token string, attending bool) (*model.Reservation, error) {
+	var reservationID string
+	var expiresAt time.Time
+	var previous *string
+	query := `SELECT reservation_id, expires_at, response FROM reservation_reminders WHERE token_hash = ?`
+	err := s.db.QueryRow(s.driver.Rebind(query), hashToken(token)).Scan(&reservationID, &expiresAt, &previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errReminderLinkInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if now.After(expiresAt) {
+		return nil, errReminderLinkInvalid
+	}
+
+	actor := Actor{Role: ActorGuest, ID: reservationID}
+	var reservation *model.Reservation
+	response := "ATTENDING"
+	if attending && previous != nil && *previous == response {
+		return s.store.GetByID(reservationID)
+	}
+	if attending {
+		reservation, err = s.store.ConfirmAttendance(actor, reservationID)
+	} else {
+		response = "CANCELED"
+		reason := "Über die Erinnerung storniert"
+		reservation, err = s.store.UpdateStatus(actor, reservationID, model.ReservationStatusCanceled, &reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	update := `UPDATE reservation_reminders SET responded_at = ?, response = ? WHERE token_hash = ?`
+	if _, err := s.db.Exec(s.driver.Rebind(update), now, response, hashToken(token)); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// DeleteExpired removes the reminders of visits that are over.
+func (s *ReminderService) DeleteExpired(now time.Time) (int64, error) {
+	result, err := s.db.Exec(s.driver.Rebind(`DELETE FROM reservation_reminders WHERE expires_at < ?`), now.Add(-24*time.Hour))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func offsetMinutes(offset time.Duration) int {
+	return int(offset / time.Minute)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"errors"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"testing"
+	"time"
+)
+
+// fakeReminderSender records the reminders instead of mailing them.
+type fakeReminderSender struct {
+	sent []string
+	fail bool
+}
+
+func (s *fakeReminderSender) SendReminder(reservation *model.Reservation, token string) error {
+	if s.fail {
+		return errors.New("mail server unavailable")
+	}
+	s.sent = append(s.sent, reservation.ID)
+	return nil
+}
+
+func TestReminderService(t *testing.T) {
+	for _, driver := range testDrivers() {
+		t.Run(string(driver), func(t *testing.T) {
+			store := openTestStore(t, driver)
+			confirmed := func(days int) *model.Reservation {
+				reservation := createTestReservation(t, store, dinnerAt(t, days), 2)
+				if _, err := store.UpdateStatus(staffActor, reservation.ID, model.ReservationStatusConfirmed, nil); err != nil {
+					t.Fatal(err)
+				}
+				return reservation
+			}
+			soon := confirmed(3)
+			later := confirmed(5)
+			unconfirmed := createTestReservation(t, store, dinnerAt(t, 3), 2)
+
+			sender := &fakeReminderSender{}
+			service := NewReminderService(ReminderConfig{Offsets: []time.Duration{24 * time.Hour, 3 * time.Hour}}, store, sender)
+			run := func(now time.Time) int {
+				t.Helper()
+				sent, err := service.Run(now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return sent
+			}
+
+			cases := []struct {
+				name string
+				now  time.Time
+				fail bool
+				want []string
+			}{
+				{"outside the lead window", soon.ReserveAt.Add(-30 * time.Hour), false, nil},
+				{"inside the larger offset", soon.ReserveAt.Add(-20 * time.Hour), false, []string{soon.ID}},
+				{"same offset again", soon.ReserveAt.Add(-19 * time.Hour), false, nil},
+				{"failed send", soon.ReserveAt.Add(-2 * time.Hour), true, nil},
+				{"retried after failure", soon.ReserveAt.Add(-2 * time.Hour), false, []string{soon.ID}},
+				{"after every offset", soon.ReserveAt.Add(-time.Hour), false, nil},
+			}
+			for _, tc := range cases {
+				sender.sent, sender.fail = nil, tc.fail
+				sent := run(tc.now)
+				if sent != len(tc.want) || len(sender.sent) != len(tc.want) {
+					t.Errorf("%s: sent %v (counted %d), want %v", tc.name, sender.sent, sent, tc.want)
+					continue
+				}
+				for i, id := range tc.want {
+					if sender.sent[i] != id {
+						t.Errorf("%s: sent %v, want %v", tc.name, sender.sent, tc.want)
+					}
+				}
+			}
+
+			for _, reservation := range []*model.Reservation{later, unconfirmed} {
+				if n := countRows(t, `SELECT COUNT(*) FROM reservation_reminders WHERE reservation_id = ?`, reservation.ID); n != 0 {
+					t.Errorf("reservation outside the window or unconfirmed has %d reminders", n)
+				}
+			}
+
+			// A new service, as after a restart, does not send again.
+			restarted := NewReminderService(service.config, store, sender)
+			sender.sent = nil
+			if sent, err := restarted.Run(soon.ReserveAt.Add(-2 * time.Hour)); err != nil || sent != 0 {
+				t.Errorf("after restart: sent %d, %v", sent, err)
+			}
+		})
+	}
+}
+
+func TestReminderTakesOverExpiredClaim(t *testing.T) {
+	for _, driver := range testDrivers() {
+		t.Run(string(driver), func(t *testing.T) {
+			store := openTestStore(t, driver)
+			reservation := createTestReservation(t, store, dinnerAt(t, 3), 2)
+			if _, err := store.UpdateStatus(staffActor, reservation.ID, model.ReservationStatusConfirmed, nil); err != nil {
+				t.Fatal(err)
+			}
+			sender := &fakeReminderSender{}
+			service := NewReminderService(ReminderConfig{Offsets: []time.Duration{24 * time.Hour}, Lease: 10 * time.Minute}, store, sender)
+
+			// A process claimed the reminder and died before sending it.
+			start := reservation.ReserveAt.Add(-20 * time.Hour)
+			if token, err := service.claim(reservation, service.config.Offsets, start); err != nil || token == "" {
+				t.Fatalf("claim: %q, %v", token, err)
+			}
+			for _, tc := range []struct {
+				after time.Duration
+				want  int
+			}{
+				{5 * time.Minute, 0},
+				{11 * time.Minute, 1},
+				{30 * time.Minute, 0},
+			} {
+				sent, err := service.Run(start.Add(tc.after))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if sent != tc.want {
+					t.Errorf("%s after the claim: sent %d, want %d", tc.after, sent, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReminderRespectsMailPreference(t *testing.T) {
+	store := openTestStore(t, database.DriverSQLite)
+	reservation := createTestReservation(t, store, dinnerAt(t, 3), 2)
+	if _, err := store.UpdateStatus(staffActor, reservation.ID, model.ReservationStatusConfirmed, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SetNotificationChannels(reservation.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	sender := &fakeReminderSender{}
+	service := NewReminderService(ReminderConfig{Offsets: []time.Duration{24 * time.Hour}}, store, sender)
+	if sent, err := service.Run(reservation.ReserveAt.Add(-20 * time.Hour)); err != nil || sent != 0 {
+		t.Errorf("sent %d, %v to a guest without mail notifications", sent, err)
+	}
+}
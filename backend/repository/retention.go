@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"strconv"
+	"time"
+)
+
+type RetentionMode string
+
+const (
+	// RetentionModeArchive moves old reservations into reservations_archive unchanged.
+	RetentionModeArchive RetentionMode = "archive"
+	// RetentionModeAnonymize moves old reservations into reservations_archive
+	// with all personal data stripped.
+	RetentionModeAnonymize RetentionMode = "anonymize"
+)
+
+const anonymizedName = "Anonym"
+
+type RetentionConfig struct {
+	// MaxAge is how long after reserveAt a reservation stays in the active table.
+	MaxAge   time.Duration
+	Mode     RetentionMode
+	Schedule string
+}
+
+// RetentionConfigFromEnv reads RETENTION_DAYS, RETENTION_MODE and RETENTION_SCHEDULE.
+func RetentionConfigFromEnv() (RetentionConfig, error) {
+	cfg := RetentionConfig{
+		MaxAge:   30 * 24 * time.Hour,
+		Mode:     RetentionModeArchive,
+		Schedule: "0 8 * * *",
+	}
+
+	if raw := os.Getenv("RETENTION_DAYS"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 1 {
+			return cfg, fmt.Errorf("invalid RETENTION_DAYS %q", raw)
+		}
+		cfg.MaxAge = time.Duration(days) * 24 * time.Hour
+	}
+	if raw := os.Getenv("RETENTION_MODE"); raw != "" {
+		switch RetentionMode(raw) {
+		case RetentionModeArchive, RetentionModeAnonymize:
+			cfg.Mode = RetentionMode(raw)
+		default:
+			return cfg, fmt.Errorf("invalid RETENTION_MODE %q, expected archive or anonymize", raw)
+		}
+	}
+	if raw := os.Getenv("RETENTION_SCHEDULE"); raw != "" {
+		cfg.Schedule = raw
+	}
+	return cfg, nil
+}
+
+type RetentionService struct {
+	db     *sql.DB
+	driver database.Driver
+	config RetentionConfig
+}
+
+func NewRetentionService(cfg RetentionConfig) *RetentionService {
+	return &RetentionService{db: database.GetDB(), driver: database.GetDriver(), config: cfg}
+}
+
+// Run moves every reservation whose reserveAt lies further in the past than
+// MaxAge into the archive. Upcoming reservations are never touched.
+func (s *RetentionService) Run(now time.Time) (int64, error) {
+	cutoff := now.Add(-s.config.MaxAge)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var insert string
+	switch s.config.Mode {
+	case RetentionModeAnonymize:
+		if err := s.redactHistory(tx, cutoff); err != nil {
+			return 0, err
+		}
+		if err := s.forgetGuests(tx, cutoff); err != nil {
+			return 0, err
+		}
+		insert = `INSERT INTO reservations_archive (id, first_name, last_name, amount, phone_number, email, created_at, reserve_at, status, notes, anonymized, archived_at)
+			SELECT id, NULL, ?, amount, '', '', created_at, reserve_at, status, NULL, ?, ? FROM reservations WHERE reserve_at < ?
+			ON CONFLICT (id) DO NOTHING`
+		_, err = tx.Exec(s.driver.Rebind(insert), anonymizedName, true, now, cutoff)
+	default:
+		insert = `INSERT INTO reservations_archive (id, first_name, last_name, amount, phone_number, email, created_at, reserve_at, status, notes, anonymized, archived_at)
+			SELECT id, first_name, last_name, amount, phone_number, email, created_at, reserve_at, status, notes, ?, ? FROM reservations WHERE reserve_at < ?
+			ON CONFLICT (id) DO NOTHING`
+		_, err = tx.Exec(s.driver.Rebind(insert), false, now, cutoff)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(s.driver.Rebind(`DELETE FROM notification_preferences WHERE reservation_id IN (SELECT id FROM reservations WHERE reserve_at < ?)`), cutoff)
+	if err != nil {
+		return 0, err
+	}
+	// Nothing queued about a reservation this old is worth sending any more.
+	_, err = tx.Exec(s.driver.Rebind(`DELETE FROM email_outbox WHERE status = ? AND reservation_id IN (SELECT id FROM reservations WHERE reserve_at < ?)`), model.EmailStatusPending, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(s.driver.Rebind(`DELETE FROM reservations WHERE reserve_at < ?`), cutoff)
+	if err != nil {
+		return 0, err
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return moved, tx.Commit()
+}
+
+// redactHistory scrubs the personal values from the audit trail of every
+// reservation about to be anonymized.
+func (s *RetentionService) redactHistory(tx *sql.Tx, cutoff time.Time) error {
+	rows, err := tx.Query(s.driver.Rebind(`SELECT id FROM reservations WHERE reserve_at < ?`), cutoff)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return redactEvents(tx, s.driver.Rebind, ids)
+}
+
+// forgetGuests deletes what else refers to the guests about to be
+// anonymized: their queued and sent messages, the sessions of their
+// reservation tokens and, unless they still have a newer reservation, their
+// email login links and sessions.
+func (s *RetentionService) forgetGuests(tx *sql.Tx, cutoff time.Time) error {
+	old := `SELECT id FROM reservations WHERE reserve_at < ?`
+	forgotten := `SELECT LOWER(email) FROM reservations WHERE reserve_at < ?
+		AND LOWER(email) NOT IN (SELECT LOWER(email) FROM reservations WHERE reserve_at >= ?)`
+	sessions := `SELECT id FROM sessions WHERE (token_type = ? AND subject IN (` + old + `)) OR (token_type = ? AND LOWER(subject) IN (` + forgotten + `))`
+	sessionArgs := []any{TokenTypeGuest, cutoff, TokenTypeGuestEmail, cutoff, cutoff}
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`DELETE FROM email_outbox WHERE reservation_id IN (` + old + `)`, []any{cutoff}},
+		{`DELETE FROM guest_login_links WHERE email IN (` + forgotten + `)`, []any{cutoff, cutoff}},
+		{`DELETE FROM refresh_tokens WHERE session_id IN (` + sessions + `)`, sessionArgs},
+		{`DELETE FROM session_tokens WHERE session_id IN (` + sessions + `)`, sessionArgs},
+		{`DELETE FROM sessions WHERE id IN (` + sessions + `)`, sessionArgs},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(s.driver.Rebind(statement.query), statement.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
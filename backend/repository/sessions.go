@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	errSessionRevoked  = errors.New("session revoked")
+	errRefreshInvalid  = errors.New("Die Sitzung ist abgelaufen. Bitte melden Sie sich erneut an.")
+	clientInfoCtxKey   = &contextKey{"client"}
+	sessionSelectQuery = `SELECT id, token_type, subject, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at FROM sessions`
+)
+
+// ClientInfo describes where a request came from. It is stored with a session
+// so staff can recognise their devices.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// clientFromRequest relies on ratelimit.Limiter.ClientAddr having replaced
+// the address of a trusted proxy with the client's.
+func clientFromRequest(r *http.Request) ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return ClientInfo{UserAgent: r.UserAgent(), IPAddress: ip}
+}
+
+func ClientForContext(ctx context.Context) ClientInfo {
+	client, _ := ctx.Value(clientInfoCtxKey).(ClientInfo)
+	return client
+}
+
+type Session struct {
+	ID         string
+	TokenType  TokenType
+	Subject    string
+	UserAgent  *string
+	IPAddress  *string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// SessionRepository stores login sessions. A session owns one rotating
+// refresh token at a time and remembers the jti of every access token issued
+// for it, so revoking the session also invalidates those.
+type SessionRepository struct {
+	db     *sql.DB
+	driver database.Driver
+}
+
+func NewSessionRepository(db *sql.DB, driver database.Driver) *SessionRepository {
+	return &SessionRepository{db: db, driver: driver}
+}
+
+func NewSessionStore() *SessionRepository {
+	return NewSessionRepository(database.GetDB(), database.GetDriver())
+}
+
+// Create starts a session and returns it with its first refresh token.
+func (r *SessionRepository) Create(tokenType TokenType, subject string, client ClientInfo, ttl time.Duration) (*Session, string, error) {
+	now := time.Now()
+	session := &Session{
+		ID:         uuid.New().String(),
+		TokenType:  tokenType,
+		Subject:    subject,
+		UserAgent:  nullableString(client.UserAgent),
+		IPAddress:  nullableString(client.IPAddress),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO sessions (id, token_type, subject, user_agent, ip_address, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(r.driver.Rebind(query), session.ID, session.TokenType, session.Subject, session.UserAgent, session.IPAddress, session.CreatedAt, session.LastSeenAt, session.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	refresh, err := r.addRefreshToken(tx, session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+	return session, refresh, nil
+}
+
+func (r *SessionRepository) addRefreshToken(q querier, sessionID string) (string, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	query := `INSERT INTO refresh_tokens (token_hash, session_id, created_at) VALUES (?, ?, ?)`
+	_, err = q.Exec(r.driver.Rebind(query), tokenHash, sessionID, time.Now())
+	return token, err
+}
+
+// Rotate exchanges a refresh token for a new one. Presenting a token that was
+// already used means it leaked, so the whole session is revoked.
+func (r *SessionRepository) Rotate(refreshToken string, client ClientInfo) (*Session, string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	var sessionID string
+	query := `SELECT session_id FROM refresh_tokens WHERE token_hash = ?`
+	err = tx.QueryRow(r.driver.Rebind(query), hashToken(refreshToken)).Scan(&sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", errRefreshInvalid
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Marking the token used is conditional, so of two concurrent rotations
+	// only one gets past here and the other counts as reuse.
+	now := time.Now()
+	query = `UPDATE refresh_tokens SET used_at = ? WHERE token_hash = ? AND used_at IS NULL`
+	result, err := tx.Exec(r.driver.Rebind(query), now, hashToken(refreshToken))
+	if err != nil {
+		return nil, "", err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, "", err
+	}
+	if n == 0 {
+		if err := r.revoke(tx, sessionID, now); err != nil {
+			return nil, "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, "", err
+		}
+		return nil, "", errRefreshInvalid
+	}
+
+	session, err := r.get(tx, sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, "", errRefreshInvalid
+	}
+
+	query = `UPDATE sessions SET last_seen_at = ?, user_agent = ?, ip_address = ? WHERE id = ?`
+	if _, err := tx.Exec(r.driver.Rebind(query), now, nullableString(client.UserAgent), nullableString(client.IPAddress), sessionID); err != nil {
+		return nil, "", err
+	}
+	refresh, err := r.addRefreshToken(tx, sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+	session.LastSeenAt = now
+	return session, refresh, nil
+}
+
+// RecordAccessToken ties an issued access token to its session.
+func (r *SessionRepository) RecordAccessToken(sessionID, jti string, expiresAt time.Time) error {
+	query := `INSERT INTO session_tokens (jti, session_id, expires_at) VALUES (?, ?, ?)`
+	_, err := r.db.Exec(r.driver.Rebind(query), jti, sessionID, expiresAt)
+	return err
+}
+
+// CheckAccessToken fails for a jti that was never issued or whose session has
+// been revoked.
+func (r *SessionRepository) CheckAccessToken(jti string) (string, error) {
+	var sessionID string
+	var revokedAt *time.Time
+	query := `SELECT s.id, s.revoked_at FROM session_tokens t JOIN sessions s ON s.id = t.session_id WHERE t.jti = ?`
+	err := r.db.QueryRow(r.driver.Rebind(query), jti).Scan(&sessionID, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errSessionRevoked
+	}
+	if err != nil {
+		return "", err
+	}
+	if revokedAt != nil {
+		return "", errSessionRevoked
+	}
+	return sessionID, nil
+}
+
+func (r *SessionRepository) Get(id string) (*Session, error) {
+	return r.get(r.db, id)
+}
+
+func (r *SessionRepository) get(q querier, id string) (*Session, error) {
+	row := q.QueryRow(r.driver.Rebind(sessionSelectQuery+` WHERE id = ?`), id)
+	session, err := scanSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("Sitzung nicht gefunden")
+	}
+	return session, err
+}
+
+// List returns the active sessions, optionally only those of one subject.
+func (r *SessionRepository) List(tokenType *TokenType, subject *string) ([]*Session, error) {
+	query := sessionSelectQuery + ` WHERE revoked_at IS NULL AND expires_at > ?`
+	args := []any{time.Now()}
+	if tokenType != nil {
+		query += ` AND token_type = ?`
+		args = append(args, *tokenType)
+	}
+	if subject != nil {
+		query += ` AND subject = ?`
+		args = append(args, *subject)
+	}
+	query += ` ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.Query(r.driver.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *SessionRepository) Revoke(id string) error {
+	return r.revoke(r.db, id, time.Now())
+}
+
+// RevokeSubject ends every session of a subject, e.g. after a password reset.
+func (r *SessionRepository) RevokeSubject(tokenType TokenType, subject string) error {
+	query := `UPDATE sessions SET revoked_at = ? WHERE token_type = ? AND subject = ? AND revoked_at IS NULL`
+	_, err := r.db.Exec(r.driver.Rebind(query), time.Now(), tokenType, subject)
+	return err
+}
+
+func (r *SessionRepository) revoke(q querier, id string, at time.Time) error {
+	_, err := q.Exec(r.driver.Rebind(`UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`), at, id)
+	return err
+}
+
+// DeleteExpired removes sessions past their expiry together with their tokens.
+func (r *SessionRepository) DeleteExpired(now time.Time) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	expired := `SELECT id FROM sessions WHERE expires_at < ?`
+	for _, table := range []string{"refresh_tokens", "session_tokens"} {
+		query := `DELETE FROM ` + table + ` WHERE session_id IN (` + expired + `)`
+		if _, err := tx.Exec(r.driver.Rebind(query), now); err != nil {
+			return 0, err
+		}
+	}
+	result, err := tx.Exec(r.driver.Rebind(`DELETE FROM sessions WHERE expires_at < ?`), now)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
+
+func scanSession(row interface{ Scan(...any) error }) (*Session, error) {
+	var s Session
+	var tokenType string
+	err := row.Scan(&s.ID, &tokenType, &s.Subject, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.TokenType = TokenType(tokenType)
+	return &s, nil
+}
+
+func (s *Session) ToModel(currentSessionID string) *model.Session {
+	return &model.Session{
+		ID:         s.ID,
+		TokenType:  string(s.TokenType),
+		Subject:    s.Subject,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    s.ID == currentSessionID,
+	}
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
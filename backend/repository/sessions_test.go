@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRotateRevokesSessionOnReuse(t *testing.T) {
+	for _, driver := range testDrivers() {
+		t.Run(string(driver), func(t *testing.T) {
+			openTestDB(t, driver)
+			sessions := NewSessionStore()
+			session, first, err := sessions.Create(TokenTypeStaff, "staff-1", ClientInfo{}, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, second, err := sessions.Rotate(first, ClientInfo{})
+			if err != nil {
+				t.Fatalf("first rotation: %v", err)
+			}
+			if _, _, err := sessions.Rotate(first, ClientInfo{}); !errors.Is(err, errRefreshInvalid) {
+				t.Fatalf("reused token: got %v, want errRefreshInvalid", err)
+			}
+			if _, _, err := sessions.Rotate(second, ClientInfo{}); !errors.Is(err, errRefreshInvalid) {
+				t.Fatalf("token of the revoked session: got %v, want errRefreshInvalid", err)
+			}
+			got, err := sessions.Get(session.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.RevokedAt == nil {
+				t.Error("session still active after reuse")
+			}
+		})
+	}
+}
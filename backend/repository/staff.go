@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	inviteValidity    = 72 * time.Hour
+	minPasswordLength = 10
+)
+
+// roleRank orders the staff roles. Only OWNER and MANAGER may manage accounts.
+// They may invite accounts ranked at or below their own, but change only
+// accounts ranked strictly below it, so peers cannot take over each other.
+var roleRank = map[model.StaffRole]int{
+	model.StaffRoleOwner:   3,
+	model.StaffRoleManager: 2,
+	model.StaffRoleHost:    1,
+}
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// dummyPasswordHash is checked against when there is no real hash, so a
+// failed login takes as long whether or not the username exists.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+type StaffUser struct {
+	ID              string
+	Username        string
+	Email           string
+	DisplayName     string
+	Role            model.StaffRole
+	PasswordHash    *string
+	Disabled        bool
+	InviteTokenHash *string
+	InviteExpiresAt *time.Time
+	CreatedAt       time.Time
+}
+
+type StaffRepository struct {
+	db     *sql.DB
+	driver database.Driver
+}
+
+func NewStaffRepository(db *sql.DB, driver database.Driver) *StaffRepository {
+	return &StaffRepository{db: db, driver: driver}
+}
+
+func NewStaffStore() *StaffRepository {
+	return NewStaffRepository(database.GetDB(), database.GetDriver())
+}
+
+const staffColumns = `id, username, email, display_name, role, password_hash, disabled, invite_token_hash, invite_expires_at, created_at`
+
+func (r *StaffRepository) scanStaff(row interface{ Scan(...any) error }) (*StaffUser, error) {
+	var s StaffUser
+	var role string
+	err := row.Scan(&s.ID, &s.Username, &s.Email, &s.DisplayName, &role, &s.PasswordHash, &s.Disabled, &s.InviteTokenHash, &s.InviteExpiresAt, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.Role = model.StaffRole(role)
+	return &s, nil
+}
+
+func (r *StaffRepository) getByID(q querier, id string) (*StaffUser, error) {
+	row := q.QueryRow(r.driver.Rebind(`SELECT `+staffColumns+` FROM staff_users WHERE id = ?`), id)
+	staff, err := r.scanStaff(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("Mitarbeiter nicht gefunden")
+	}
+	return staff, err
+}
+
+func (r *StaffRepository) GetByID(id string) (*StaffUser, error) {
+	return r.getByID(r.db, id)
+}
+
+func (r *StaffRepository) List() ([]*StaffUser, error) {
+	rows, err := r.db.Query(`SELECT ` + staffColumns + ` FROM staff_users ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var staff []*StaffUser
+	for rows.Next() {
+		s, err := r.scanStaff(rows)
+		if err != nil {
+			return nil, err
+		}
+		staff = append(staff, s)
+	}
+	return staff, rows.Err()
+}
+
+// Authenticate checks a username and password. Unknown users, disabled users
+// and users that never accepted their invite all fail the same way.
+func (r *StaffRepository) Authenticate(username, password string) (*StaffUser, error) {
+	row := r.db.QueryRow(r.driver.Rebind(`SELECT `+staffColumns+` FROM staff_users WHERE username = ?`), strings.TrimSpace(username))
+	staff, err := r.scanStaff(row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	hash := dummyPasswordHash()
+	if staff != nil && staff.PasswordHash != nil {
+		hash = []byte(*staff.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || staff == nil || staff.Disabled || staff.PasswordHash == nil {
+		return nil, errInvalidCredentials
+	}
+	return staff, nil
+}
+
+// EnsureOwner creates an OWNER account from the given credentials when the
+// table is still empty, so an existing ADMIN_USERNAME/ADMIN_PASSWORD setup
+// keeps working after the upgrade. It does nothing once any staff user exists.
+func (r *StaffRepository) EnsureOwner(username, password string) error {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM staff_users`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 || username == "" || password == "" {
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	query := `INSERT INTO staff_users (id, username, email, display_name, role, password_hash, disabled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.db.Exec(r.driver.Rebind(query), uuid.New().String(), username, "", username, model.StaffRoleOwner, string(hash), false, now, now)
+	return err
+}
+
+// Invite creates an account without a password and returns the one-time token
+// the new staff member uses to set it.
+func (r *StaffRepository) Invite(by *User, input model.InviteStaff) (*StaffUser, string, error) {
+	username := strings.TrimSpace(input.Username)
+	if username == "" {
+		return nil, "", fmt.Errorf("Benutzername ist erforderlich")
+	}
+	if strings.TrimSpace(input.DisplayName) == "" {
+		return nil, "", fmt.Errorf("Name ist erforderlich")
+	}
+	if err := checkManages(by, input.Role); err != nil {
+		return nil, "", err
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	staff := &StaffUser{
+		ID:              uuid.New().String(),
+		Username:        username,
+		Email:           strings.TrimSpace(input.Email),
+		DisplayName:     strings.TrimSpace(input.DisplayName),
+		Role:            input.Role,
+		InviteTokenHash: &tokenHash,
+		CreatedAt:       now,
+	}
+	expires := now.Add(inviteValidity)
+	staff.InviteExpiresAt = &expires
+
+	var exists int
+	if err := r.db.QueryRow(r.driver.Rebind(`SELECT COUNT(*) FROM staff_users WHERE username = ?`), username).Scan(&exists); err != nil {
+		return nil, "", err
+	}
+	if exists > 0 {
+		return nil, "", fmt.Errorf("Der Benutzername %q ist bereits vergeben", username)
+	}
+
+	query := `INSERT INTO staff_users (id, username, email, display_name, role, disabled, invite_token_hash, invite_expires_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.db.Exec(r.driver.Rebind(query), staff.ID, staff.Username, staff.Email, staff.DisplayName, staff.Role, false, tokenHash, expires, now, now)
+	if err != nil {
+		return nil, "", err
+	}
+	return staff, token, nil
+}
+
+// AcceptInvite sets the password of the account the token was issued for.
+// Password resets use the same token flow.
+func (r *StaffRepository) AcceptInvite(token, password string) (*StaffUser, error) {
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("Das Passwort muss mindestens %d Zeichen lang sein.", minPasswordLength)
+	}
+
+	row := r.db.QueryRow(r.driver.Rebind(`SELECT `+staffColumns+` FROM staff_users WHERE invite_token_hash = ?`), hashToken(token))
+	staff, err := r.scanStaff(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("Der Einladungslink ist ungültig.")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if staff.Disabled {
+		return nil, fmt.Errorf("Dieses Konto ist deaktiviert.")
+	}
+	if staff.InviteExpiresAt == nil || time.Now().After(*staff.InviteExpiresAt) {
+		return nil, fmt.Errorf("Der Einladungslink ist abgelaufen.")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	query := `UPDATE staff_users SET password_hash = ?, invite_token_hash = NULL, invite_expires_at = NULL, updated_at = ? WHERE id = ?`
+	if _, err := r.db.Exec(r.driver.Rebind(query), string(hash), time.Now(), staff.ID); err != nil {
+		return nil, err
+	}
+	return r.GetByID(staff.ID)
+}
+
+// ResetPassword clears the password, which locks the account out right away,
+// and issues a fresh token to set a new one. The token is for the account's
+// owner only and must be mailed to them, never shown to the caller.
+func (r *StaffRepository) ResetPassword(by *User, id string) (*StaffUser, string, error) {
+	staff, err := r.GetByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkOutranksOrSelf(by, staff); err != nil {
+		return nil, "", err
+	}
+	if staff.Email == "" {
+		return nil, "", fmt.Errorf("Für dieses Konto ist keine E-Mail-Adresse hinterlegt.")
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	expires := time.Now().Add(inviteValidity)
+	query := `UPDATE staff_users SET password_hash = NULL, invite_token_hash = ?, invite_expires_at = ?, updated_at = ? WHERE id = ?`
+	if _, err := r.db.Exec(r.driver.Rebind(query), tokenHash, expires, time.Now(), id); err != nil {
+		return nil, "", err
+	}
+	staff, err = r.GetByID(id)
+	return staff, token, err
+}
+
+func (r *StaffRepository) SetDisabled(by *User, id string, disabled bool) (*StaffUser, error) {
+	if id == by.StaffID {
+		return nil, fmt.Errorf("Sie können Ihr eigenes Konto nicht deaktivieren.")
+	}
+	return r.updateManaged(by, id, func(tx *sql.Tx, staff *StaffUser) error {
+		_, err := tx.Exec(r.driver.Rebind(`UPDATE staff_users SET disabled = ?, updated_at = ? WHERE id = ?`), disabled, time.Now(), id)
+		return err
+	})
+}
+
+func (r *StaffRepository) ChangeRole(by *User, id string, role model.StaffRole) (*StaffUser, error) {
+	if _, ok := roleRank[role]; !ok {
+		return nil, fmt.Errorf("Unbekannte Rolle %s", role)
+	}
+	if id == by.StaffID {
+		return nil, fmt.Errorf("Sie können Ihre eigene Rolle nicht ändern.")
+	}
+	if err := checkManages(by, role); err != nil {
+		return nil, err
+	}
+	return r.updateManaged(by, id, func(tx *sql.Tx, staff *StaffUser) error {
+		_, err := tx.Exec(r.driver.Rebind(`UPDATE staff_users SET role = ?, updated_at = ? WHERE id = ?`), role, time.Now(), id)
+		return err
+	})
+}
+
+// updateManaged loads the target account in a transaction, checks that by
+// outranks it and applies change. As nobody outranks an owner, owners can
+// neither be disabled nor demoted, and the restaurant always keeps one.
+func (r *StaffRepository) updateManaged(by *User, id string, change func(tx *sql.Tx, staff *StaffUser) error) (*StaffUser, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	staff, err := r.getByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOutranks(by, staff.Role); err != nil {
+		return nil, err
+	}
+	if err := change(tx, staff); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+func checkManages(by *User, target model.StaffRole) error {
+	if by == nil || !by.IsAdmin {
+		return fmt.Errorf("Unauthenticated")
+	}
+	if by.Role != model.StaffRoleOwner && by.Role != model.StaffRoleManager {
+		return fmt.Errorf("Nur Inhaber und Manager dürfen Mitarbeiter verwalten.")
+	}
+	if roleRank[target] > roleRank[by.Role] {
+		return fmt.Errorf("Sie dürfen keine Konten mit der Rolle %s verwalten.", target)
+	}
+	return nil
+}
+
+// checkOutranks allows by to change accounts ranked strictly below their own.
+func checkOutranks(by *User, target model.StaffRole) error {
+	if err := checkManages(by, target); err != nil {
+		return err
+	}
+	if roleRank[target] == roleRank[by.Role] {
+		return fmt.Errorf("Sie dürfen keine Konten mit Ihrer eigenen Rolle %s verwalten.", target)
+	}
+	return nil
+}
+
+// checkOutranksOrSelf also lets staff act on their own account.
+func checkOutranksOrSelf(by *User, staff *StaffUser) error {
+	if by != nil && by.IsAdmin && by.StaffID == staff.ID {
+		return nil
+	}
+	return checkOutranks(by, staff.Role)
+}
+
+func newOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *StaffUser) ToModel() *model.StaffUser {
+	return &model.StaffUser{
+		ID:            s.ID,
+		Username:      s.Username,
+		Email:         s.Email,
+		DisplayName:   s.DisplayName,
+		Role:          s.Role,
+		Disabled:      s.Disabled,
+		PendingInvite: s.PasswordHash == nil,
+		CreatedAt:     s.CreatedAt,
+	}
+}
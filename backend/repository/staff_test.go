@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"testing"
+)
+
+func TestStaffManagesOnlyLowerRanks(t *testing.T) {
+	openTestDB(t, database.DriverSQLite)
+	staff := NewStaffStore()
+	if err := staff.EnsureOwner("inhaberin", "supersecret1"); err != nil {
+		t.Fatal(err)
+	}
+	owners, err := staff.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := &User{StaffID: owners[0].ID, Role: model.StaffRoleOwner, IsAdmin: true}
+
+	invite := func(username string, role model.StaffRole) (*StaffUser, *User) {
+		t.Helper()
+		account, _, err := staff.Invite(owner, model.InviteStaff{Username: username, Email: username + "@yoake.example", DisplayName: username, Role: role})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return account, &User{StaffID: account.ID, Role: role, IsAdmin: true}
+	}
+	manager, managerUser := invite("manager", model.StaffRoleManager)
+	peer, _ := invite("kollege", model.StaffRoleManager)
+	host, _ := invite("service", model.StaffRoleHost)
+	secondOwner, _ := invite("mitinhaber", model.StaffRoleOwner)
+
+	cases := []struct {
+		name    string
+		action  func() error
+		allowed bool
+	}{
+		{"manager resets another manager", func() error { _, _, err := staff.ResetPassword(managerUser, peer.ID); return err }, false},
+		{"manager disables another manager", func() error { _, err := staff.SetDisabled(managerUser, peer.ID, true); return err }, false},
+		{"manager demotes another manager", func() error {
+			_, err := staff.ChangeRole(managerUser, peer.ID, model.StaffRoleHost)
+			return err
+		}, false},
+		{"manager resets an owner", func() error { _, _, err := staff.ResetPassword(managerUser, owners[0].ID); return err }, false},
+		{"owner resets another owner", func() error { _, _, err := staff.ResetPassword(owner, secondOwner.ID); return err }, false},
+		{"owner disables another owner", func() error { _, err := staff.SetDisabled(owner, secondOwner.ID, true); return err }, false},
+		{"manager resets own password", func() error { _, _, err := staff.ResetPassword(managerUser, manager.ID); return err }, true},
+		{"manager resets a host", func() error { _, _, err := staff.ResetPassword(managerUser, host.ID); return err }, true},
+		{"manager disables a host", func() error { _, err := staff.SetDisabled(managerUser, host.ID, true); return err }, true},
+		{"owner demotes a manager", func() error {
+			_, err := staff.ChangeRole(owner, peer.ID, model.StaffRoleHost)
+			return err
+		}, true},
+	}
+	for _, tc := range cases {
+		err := tc.action()
+		if tc.allowed && err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if !tc.allowed && err == nil {
+			t.Errorf("%s succeeded", tc.name)
+		}
+	}
+
+	// The initial owner has no email address, so a reset could not reach them.
+	if _, _, err := staff.ResetPassword(owner, owner.StaffID); err == nil {
+		t.Error("reset of an account without email succeeded")
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"revervation/backend/graph/model"
+	"slices"
+)
+
+type ActorRole string
+
+const (
+	ActorGuest ActorRole = "GUEST"
+	ActorAdmin ActorRole = "ADMIN"
+)
+
+// Actor is whoever triggers a change: a guest holding a reservation token or
+// a member of staff.
+type Actor struct {
+	Role ActorRole
+	ID   string
+}
+
+// statusTransitions lists, per current status, the statuses a reservation may
+// move to and who may trigger the move. CANCELED and COMPLETED are terminal; a
+// DECLINED reservation can only be reopened by staff. The floor statuses
+// SEATED, COMPLETED and NO_SHOW are staff-only; a guest marked as no-show who
+// turns up late can still be seated.
+var statusTransitions = map[model.ReservationStatus]map[model.ReservationStatus][]ActorRole{
+	model.ReservationStatusOpen: {
+		model.ReservationStatusConfirmed: {ActorAdmin},
+		model.ReservationStatusDeclined:  {ActorAdmin},
+		model.ReservationStatusCanceled:  {ActorGuest, ActorAdmin},
+		model.ReservationStatusSeated:    {ActorAdmin},
+		model.ReservationStatusNoShow:    {ActorAdmin},
+	},
+	model.ReservationStatusConfirmed: {
+		model.ReservationStatusOpen:     {ActorAdmin},
+		model.ReservationStatusDeclined: {ActorAdmin},
+		model.ReservationStatusCanceled: {ActorGuest, ActorAdmin},
+		model.ReservationStatusSeated:   {ActorAdmin},
+		model.ReservationStatusNoShow:   {ActorAdmin},
+	},
+	model.ReservationStatusDeclined: {
+		model.ReservationStatusOpen: {ActorAdmin},
+	},
+	model.ReservationStatusSeated: {
+		model.ReservationStatusCompleted: {ActorAdmin},
+	},
+	model.ReservationStatusNoShow: {
+		model.ReservationStatusSeated: {ActorAdmin},
+	},
+	model.ReservationStatusCanceled:  {},
+	model.ReservationStatusCompleted: {},
+}
+
+func checkTransition(from, to model.ReservationStatus, actor Actor) error {
+	roles, ok := statusTransitions[from][to]
+	if !ok {
+		return &InvalidTransitionError{From: from, To: to}
+	}
+	if !slices.Contains(roles, actor.Role) {
+		return &TransitionForbiddenError{From: from, To: to, Role: actor.Role}
+	}
+	return nil
+}
+
+func isEditable(status model.ReservationStatus) bool {
+	return status == model.ReservationStatusOpen || status == model.ReservationStatusConfirmed
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"fmt"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"time"
+)
+
+type ReservationStore interface {
+	Create(actor Actor, reservation *model.Reservation, channels []model.NotificationChannel) error
+	Update(actor Actor, id string, firstName, lastName *string, amount *int32, reserveAt *time.Time, notes *string, phoneNumber *string, email *string) (*model.Reservation, error)
+	GetByID(id string) (*model.Reservation, error)
+	GetAll() ([]*model.Reservation, error)
+	GetByFilter(filter model.ReservationFilter) ([]*model.Reservation, error)
+	GetAllByFilter(filter model.ReservationFilter) ([]*model.Reservation, error)
+	GetArchivedByFilter(filter model.ReservationFilter) ([]*model.Reservation, error)
+	GetUpcomingByEmail(email string) ([]*model.Reservation, error)
+	GetConfirmedBetween(from, to time.Time) ([]*model.Reservation, error)
+	UpdateStatus(actor Actor, id string, status model.ReservationStatus, reason *string) (*model.Reservation, error)
+	DeclineWithMessage(actor Actor, id string, reason *string, message string) (*model.Reservation, error)
+	ConfirmAttendance(actor Actor, id string) (*model.Reservation, error)
+	GetStats(date *time.Time) (*model.ReservationInfo, error)
+	GetAvailability(date time.Time, partySize int32) ([]*model.AvailableSlot, error)
+	History(id string) ([]*model.ReservationEvent, error)
+	NotificationChannels(id string) ([]model.NotificationChannel, error)
+	SetNotificationChannels(id string, channels []model.NotificationChannel) (*model.Reservation, error)
+}
+
+// NewReservationStore returns the store matching the configured database
+// driver. Guests can choose among channels to be notified on.
+func NewReservationStore(channels []model.NotificationChannel) (ReservationStore, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	switch database.GetDriver() {
+	case database.DriverSQLite:
+		return NewSQLiteReservationRepository(db, channels), nil
+	case database.DriverPostgres:
+		return NewPostgresReservationRepository(db, channels), nil
+	default:
+		return nil, fmt.Errorf("no reservation store for driver %q", database.GetDriver())
+	}
+}
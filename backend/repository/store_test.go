@@ -0,0 +1,497 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"revervation/backend/database"
+	"revervation/backend/graph/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The store tests run against SQLite and, when TEST_POSTGRES_DSN points to a
+// server (e.g. a local container started with
+// `docker run -e POSTGRES_PASSWORD=test -p 5432:5432 postgres`), against
+// PostgreSQL as well. Every test gets an empty, fully migrated database.
+
+var staffActor = Actor{Role: ActorAdmin, ID: "staff-1"}
+
+func testDrivers() []database.Driver {
+	return []database.Driver{database.DriverSQLite, database.DriverPostgres}
+}
+
+// openTestDB initialises the global database with a fresh schema.
+func openTestDB(t *testing.T, driver database.Driver) {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	if driver == database.DriverPostgres {
+		dsn = postgresTestDSN(t)
+	}
+	if err := database.Init(driver, dsn); err != nil {
+		t.Fatalf("init %s: %v", driver, err)
+	}
+	t.Cleanup(func() { database.Close() })
+}
+
+// postgresTestDSN creates a schema of its own for the test and returns a DSN
+// that uses it, so tests never see each other's rows.
+func postgresTestDSN(t *testing.T) string {
+	t.Helper()
+	base := os.Getenv("TEST_POSTGRES_DSN")
+	if base == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	admin, err := sql.Open(string(database.DriverPostgres), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		admin.Close()
+	})
+
+	if strings.Contains(base, "://") {
+		u, err := url.Parse(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return base + " search_path=" + schema
+}
+
+func openTestStore(t *testing.T, driver database.Driver) ReservationStore {
+	t.Helper()
+	openTestDB(t, driver)
+	store, err := NewReservationStore(model.AllNotificationChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// dinnerAt returns 19:00 restaurant time, days from today. The seeded
+// opening hours serve dinner every day.
+func dinnerAt(t *testing.T, days int) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Now().In(loc).AddDate(0, 0, days)
+	return time.Date(day.Year(), day.Month(), day.Day(), 19, 0, 0, 0, loc)
+}
+
+func newTestReservation(reserveAt time.Time, amount int32) *model.Reservation {
+	firstName := "Erika"
+	return &model.Reservation{
+		ID:          uuid.New().String(),
+		FirstName:   &firstName,
+		LastName:    "Mustermann",
+		PhoneNumber: "+49 171 1234567",
+		Email:       "erika@example.com",
+		Amount:      amount,
+		CreatedAt:   time.Now(),
+		ReserveAt:   reserveAt,
+		Status:      model.ReservationStatusOpen,
+	}
+}
+
+func createTestReservation(t *testing.T, store ReservationStore, reserveAt time.Time, amount int32) *model.Reservation {
+	t.Helper()
+	reservation := newTestReservation(reserveAt, amount)
+	if err := store.Create(Actor{Role: ActorGuest}, reservation, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return reservation
+}
+
+func countRows(t *testing.T, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := database.GetDB().QueryRow(database.GetDriver().Rebind(query), args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func eventTypes(t *testing.T, store ReservationStore, id string) []model.ReservationEventType {
+	t.Helper()
+	events, err := store.History(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []model.ReservationEventType
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+var storeCases = []struct {
+	name string
+	run  func(t *testing.T, store ReservationStore)
+}{
+	{"create", testCreate},
+	{"create validation", testCreateValidation},
+	{"update", testUpdate},
+	{"capacity", testCapacity},
+	{"status transitions", testStatusTransitions},
+	{"archive", testArchive},
+	{"anonymize", testAnonymize},
+}
+
+func TestReservationStore(t *testing.T) {
+	for _, driver := range testDrivers() {
+		t.Run(string(driver), func(t *testing.T) {
+			for _, tc := range storeCases {
+				t.Run(tc.name, func(t *testing.T) {
+					tc.run(t, openTestStore(t, driver))
+				})
+			}
+		})
+	}
+}
+
+func testCreate(t *testing.T, store ReservationStore) {
+	reserveAt := dinnerAt(t, 3)
+	created := createTestReservation(t, store, reserveAt, 4)
+
+	got, err := store.GetByID(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LastName != "Mustermann" || got.Amount != 4 || got.Status != model.ReservationStatusOpen || !got.ReserveAt.Equal(reserveAt) {
+		t.Errorf("stored reservation = %+v", got)
+	}
+	if types := eventTypes(t, store, created.ID); len(types) != 1 || types[0] != model.ReservationEventTypeCreated {
+		t.Errorf("history = %v, want [CREATED]", types)
+	}
+	channels, err := store.NotificationChannels(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 1 || channels[0] != model.NotificationChannelEmail {
+		t.Errorf("channels = %v, want [EMAIL]", channels)
+	}
+	if n := countRows(t, `SELECT COUNT(*) FROM email_outbox WHERE reservation_id = ? AND event = ?`, created.ID, model.ReservationEventBroadcastCreated); n != 1 {
+		t.Errorf("queued %d mails, want 1", n)
+	}
+}
+
+func testCreateValidation(t *testing.T, store ReservationStore) {
+	cases := []struct {
+		name   string
+		modify func(r *model.Reservation)
+	}{
+		{"past", func(r *model.Reservation) { r.ReserveAt = time.Now().Add(-time.Hour) }},
+		{"no last name", func(r *model.Reservation) { r.LastName = "" }},
+		{"invalid email", func(r *model.Reservation) { r.Email = "erika@" }},
+		{"invalid phone", func(r *model.Reservation) { r.PhoneNumber = "12" }},
+		{"no guests", func(r *model.Reservation) { r.Amount = 0 }},
+		{"closed", func(r *model.Reservation) { r.ReserveAt = r.ReserveAt.Add(-10 * time.Hour) }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reservation := newTestReservation(dinnerAt(t, 3), 2)
+			tc.modify(reservation)
+			if err := store.Create(Actor{Role: ActorGuest}, reservation, nil); err == nil {
+				t.Fatal("create succeeded")
+			}
+			if n := countRows(t, `SELECT COUNT(*) FROM reservations WHERE id = ?`, reservation.ID); n != 0 {
+				t.Errorf("invalid reservation was stored")
+			}
+		})
+	}
+}
+
+func testUpdate(t *testing.T, store ReservationStore) {
+	created := createTestReservation(t, store, dinnerAt(t, 3), 2)
+	notes := "Fensterplatz"
+	amount := int32(3)
+	updated, err := store.Update(staffActor, created.ID, nil, nil, &amount, nil, &notes, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Amount != 3 || updated.Notes == nil || *updated.Notes != notes {
+		t.Errorf("updated reservation = %+v", updated)
+	}
+
+	// A guest moving a confirmed booking sends it back for confirmation.
+	if _, err := store.UpdateStatus(staffActor, created.ID, model.ReservationStatusConfirmed, nil); err != nil {
+		t.Fatal(err)
+	}
+	later := dinnerAt(t, 4)
+	guest := Actor{Role: ActorGuest, ID: created.ID}
+	moved, err := store.Update(guest, created.ID, nil, nil, nil, &later, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Status != model.ReservationStatusOpen || !moved.ReserveAt.Equal(later) {
+		t.Errorf("moved reservation = %+v, want OPEN at %s", moved, later)
+	}
+
+	events, err := store.History(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := events[len(events)-1]
+	if last.Type != model.ReservationEventTypeUpdated || last.ActorRole != string(ActorGuest) {
+		t.Errorf("last event = %+v", last)
+	}
+	fields := map[string]bool{}
+	for _, change := range last.Changes {
+		fields[change.Field] = true
+	}
+	if !fields["reserveAt"] || !fields["status"] {
+		t.Errorf("changes = %v, want reserveAt and status", fields)
+	}
+
+	if _, err := store.UpdateStatus(staffActor, created.ID, model.ReservationStatusCanceled, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update(staffActor, created.ID, nil, nil, &amount, nil, nil, nil, nil); err == nil {
+		t.Error("canceled reservation was updated")
+	}
+}
+
+func testCapacity(t *testing.T, store ReservationStore) {
+	// The seeded limit is 40 covers and 12 reservations per slot.
+	reserveAt := dinnerAt(t, 3)
+	big := createTestReservation(t, store, reserveAt, 38)
+
+	err := store.Create(Actor{Role: ActorGuest}, newTestReservation(reserveAt, 3), nil)
+	var full *SlotFullError
+	if !errors.As(err, &full) {
+		t.Fatalf("create = %v, want SlotFullError", err)
+	}
+	if full.RemainingCovers != 2 {
+		t.Errorf("remaining covers = %d, want 2", full.RemainingCovers)
+	}
+	fits := createTestReservation(t, store, reserveAt, 2)
+
+	// Canceling frees the covers, and they are checked again on reopening.
+	if _, err := store.UpdateStatus(staffActor, fits.ID, model.ReservationStatusCanceled, nil); err != nil {
+		t.Fatal(err)
+	}
+	other := createTestReservation(t, store, reserveAt, 2)
+	amount := int32(3)
+	if _, err := store.Update(staffActor, other.ID, nil, nil, &amount, nil, nil, nil, nil); !errors.As(err, &full) {
+		t.Errorf("growing past the limit = %v, want SlotFullError", err)
+	}
+	if _, err := store.UpdateStatus(staffActor, big.ID, model.ReservationStatusDeclined, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.UpdateStatus(staffActor, other.ID, model.ReservationStatusConfirmed, nil); err != nil {
+		t.Fatal(err)
+	}
+	createTestReservation(t, store, reserveAt, 30)
+	if _, err := store.UpdateStatus(staffActor, big.ID, model.ReservationStatusOpen, nil); !errors.As(err, &full) {
+		t.Errorf("reopening into a full slot = %v, want SlotFullError", err)
+	}
+
+	nextDay := dinnerAt(t, 4)
+	for i := 0; i < 12; i++ {
+		createTestReservation(t, store, nextDay, 1)
+	}
+	if err := store.Create(Actor{Role: ActorGuest}, newTestReservation(nextDay, 1), nil); !errors.As(err, &full) {
+		t.Errorf("13th reservation = %v, want SlotFullError", err)
+	}
+}
+
+func testStatusTransitions(t *testing.T, store ReservationStore) {
+	guest := Actor{Role: ActorGuest}
+	cases := []struct {
+		name    string
+		path    []model.ReservationStatus
+		actor   Actor
+		to      model.ReservationStatus
+		wantErr any
+	}{
+		{"staff confirms", nil, staffActor, model.ReservationStatusConfirmed, nil},
+		{"guest cannot confirm", nil, guest, model.ReservationStatusConfirmed, &TransitionForbiddenError{}},
+		{"guest cancels", []model.ReservationStatus{model.ReservationStatusConfirmed}, guest, model.ReservationStatusCanceled, nil},
+		{"staff reopens declined", []model.ReservationStatus{model.ReservationStatusDeclined}, staffActor, model.ReservationStatusOpen, nil},
+		{"guest cannot reopen declined", []model.ReservationStatus{model.ReservationStatusDeclined}, guest, model.ReservationStatusOpen, &TransitionForbiddenError{}},
+		{"canceled is final", []model.ReservationStatus{model.ReservationStatusCanceled}, staffActor, model.ReservationStatusOpen, &InvalidTransitionError{}},
+		{"seated completes", []model.ReservationStatus{model.ReservationStatusSeated}, staffActor, model.ReservationStatusCompleted, nil},
+		{"late no-show is seated", []model.ReservationStatus{model.ReservationStatusNoShow}, staffActor, model.ReservationStatusSeated, nil},
+		{"completed is final", []model.ReservationStatus{model.ReservationStatusSeated, model.ReservationStatusCompleted}, staffActor, model.ReservationStatusNoShow, &InvalidTransitionError{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reservation := createTestReservation(t, store, dinnerAt(t, 3), 2)
+			for _, status := range tc.path {
+				if _, err := store.UpdateStatus(staffActor, reservation.ID, status, nil); err != nil {
+					t.Fatalf("moving to %s: %v", status, err)
+				}
+			}
+			before := len(eventTypes(t, store, reservation.ID))
+
+			updated, err := store.UpdateStatus(tc.actor, reservation.ID, tc.to, nil)
+			switch want := tc.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if updated.Status != tc.to {
+					t.Errorf("status = %s, want %s", updated.Status, tc.to)
+				}
+				if after := len(eventTypes(t, store, reservation.ID)); after != before+1 {
+					t.Errorf("recorded %d events, want 1", after-before)
+				}
+			case *TransitionForbiddenError:
+				if !errors.As(err, &want) {
+					t.Fatalf("err = %v, want TransitionForbiddenError", err)
+				}
+			case *InvalidTransitionError:
+				if !errors.As(err, &want) {
+					t.Fatalf("err = %v, want InvalidTransitionError", err)
+				}
+			}
+			if tc.wantErr != nil {
+				if after := len(eventTypes(t, store, reservation.ID)); after != before {
+					t.Errorf("a rejected transition recorded %d events", after-before)
+				}
+			}
+		})
+	}
+}
+
+// runRetention archives every reservation that is at least a day over,
+// seen from after the last test reservation.
+func runRetention(t *testing.T, mode RetentionMode, now time.Time) int64 {
+	t.Helper()
+	service := NewRetentionService(RetentionConfig{MaxAge: 24 * time.Hour, Mode: mode})
+	moved, err := service.Run(now)
+	if err != nil {
+		t.Fatalf("retention: %v", err)
+	}
+	return moved
+}
+
+func testArchive(t *testing.T, store ReservationStore) {
+	old := createTestReservation(t, store, dinnerAt(t, 2), 2)
+	upcoming := createTestReservation(t, store, dinnerAt(t, 10), 2)
+
+	if moved := runRetention(t, RetentionModeArchive, dinnerAt(t, 5)); moved != 1 {
+		t.Fatalf("moved %d reservations, want 1", moved)
+	}
+	if _, err := store.GetByID(old.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("archived reservation is still active: %v", err)
+	}
+	if _, err := store.GetByID(upcoming.ID); err != nil {
+		t.Errorf("upcoming reservation was archived: %v", err)
+	}
+
+	archived, err := store.GetArchivedByFilter(model.ReservationFilter{ID: &old.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 1 || archived[0].LastName != "Mustermann" || archived[0].Email != "erika@example.com" {
+		t.Fatalf("archive = %+v", archived)
+	}
+	if n := countRows(t, `SELECT COUNT(*) FROM notification_preferences WHERE reservation_id = ?`, old.ID); n != 0 {
+		t.Errorf("preferences of the archived reservation were kept")
+	}
+	if n := countRows(t, `SELECT COUNT(*) FROM email_outbox WHERE reservation_id = ? AND status = ?`, old.ID, model.EmailStatusPending); n != 0 {
+		t.Errorf("%d messages about the archived reservation still queued", n)
+	}
+
+	// Running again moves nothing.
+	if moved := runRetention(t, RetentionModeArchive, dinnerAt(t, 5)); moved != 0 {
+		t.Errorf("second run moved %d reservations", moved)
+	}
+}
+
+func testAnonymize(t *testing.T, store ReservationStore) {
+	old := createTestReservation(t, store, dinnerAt(t, 2), 2)
+	email := "erika@example.com"
+	phone := "0171 7654321"
+	if _, err := store.Update(staffActor, old.ID, nil, nil, nil, nil, nil, &phone, &email); err != nil {
+		t.Fatal(err)
+	}
+	links := NewGuestLinkStore()
+	if _, err := links.Create(email); err != nil {
+		t.Fatal(err)
+	}
+	sessions := NewSessionStore()
+	if _, _, err := sessions.Create(TokenTypeGuest, old.ID, ClientInfo{}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sessions.Create(TokenTypeGuestEmail, email, ClientInfo{}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// A guest with a newer booking under another address keeps it.
+	other := newTestReservation(dinnerAt(t, 10), 2)
+	other.Email = "max@example.com"
+	if err := store.Create(Actor{Role: ActorGuest}, other, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := links.Create(other.Email); err != nil {
+		t.Fatal(err)
+	}
+
+	if moved := runRetention(t, RetentionModeAnonymize, dinnerAt(t, 5)); moved != 1 {
+		t.Fatalf("moved %d reservations, want 1", moved)
+	}
+
+	archived, err := store.GetArchivedByFilter(model.ReservationFilter{ID: &old.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 1 {
+		t.Fatalf("archive = %+v", archived)
+	}
+	got := archived[0]
+	if got.LastName != anonymizedName || got.FirstName != nil || got.Email != "" || got.PhoneNumber != "" || got.Notes != nil {
+		t.Errorf("anonymized reservation = %+v", got)
+	}
+
+	events, err := store.History(old.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range events {
+		for _, change := range event.Changes {
+			if personalFields[change.Field] && (change.Before != nil || change.After != nil) {
+				t.Errorf("%s event keeps %s", event.Type, change.Field)
+			}
+		}
+	}
+
+	checks := []struct {
+		what  string
+		query string
+		args  []any
+		want  int
+	}{
+		{"outbox mails", `SELECT COUNT(*) FROM email_outbox WHERE reservation_id = ?`, []any{old.ID}, 0},
+		{"login links", `SELECT COUNT(*) FROM guest_login_links WHERE email = ?`, []any{email}, 0},
+		{"reservation sessions", `SELECT COUNT(*) FROM sessions WHERE subject = ?`, []any{old.ID}, 0},
+		{"email sessions", `SELECT COUNT(*) FROM sessions WHERE subject = ?`, []any{email}, 0},
+		{"other guest's links", `SELECT COUNT(*) FROM guest_login_links WHERE email = ?`, []any{other.Email}, 1},
+		{"other guest's mails", `SELECT COUNT(*) FROM email_outbox WHERE reservation_id = ?`, []any{other.ID}, 1},
+	}
+	for _, check := range checks {
+		if n := countRows(t, check.query, check.args...); n != check.want {
+			t.Errorf("%s: %d rows, want %d", check.what, n, check.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type TokenType string
+
+const (
+	TokenTypeStaff TokenType = "staff"
+	TokenTypeGuest TokenType = "guest"
+	// TokenTypeGuestEmail is held by a guest who signed in with an emailed
+	// link. It covers every upcoming reservation of that address.
+	TokenTypeGuestEmail TokenType = "guest_email"
+	// TokenTypeTOTPChallenge proves the password step of a staff login. It
+	// is only good for completing the login with a second factor.
+	TokenTypeTOTPChallenge TokenType = "totp_challenge"
+)
+
+const (
+	defaultIssuer   = "reservation-backend"
+	defaultAudience = "reservation"
+)
+
+// allowedAlgorithms is the signing method allowlist. Tokens using anything
+// else, including "none", are rejected before the key is looked up.
+var allowedAlgorithms = []string{jwt.SigningMethodHS256.Alg()}
+
+// Claims is the payload of every token the backend issues. Subject is the
+// staff user ID for staff tokens, the reservation ID for guest tokens and the
+// lowercased email address for guest email tokens.
+type Claims struct {
+	jwt.RegisteredClaims
+	Type      TokenType `json:"typ"`
+	Role      string    `json:"role,omitempty"`
+	SessionID string    `json:"sid,omitempty"`
+}
+
+// KeyRing holds the HMAC keys by kid. Tokens are signed with the active key;
+// every key in the ring is accepted, so an old key can stay until the tokens
+// signed with it have expired.
+type KeyRing struct {
+	keys   map[string][]byte
+	active string
+}
+
+// KeyRingFromEnv reads JWT_KEYS as a comma separated list of kid:secret pairs
+// and JWT_ACTIVE_KID to pick the signing key, defaulting to the first pair.
+// Without JWT_KEYS, JWT_SECRET becomes the single key with kid "default".
+func KeyRingFromEnv() (*KeyRing, error) {
+	ring := &KeyRing{keys: map[string][]byte{}}
+
+	raw := os.Getenv("JWT_KEYS")
+	if raw == "" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, fmt.Errorf("JWT_KEYS or JWT_SECRET must be set")
+		}
+		ring.keys["default"] = []byte(secret)
+		ring.active = "default"
+		return ring, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || kid == "" || secret == "" {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid:secret", pair)
+		}
+		if _, exists := ring.keys[kid]; exists {
+			return nil, fmt.Errorf("duplicate kid %q in JWT_KEYS", kid)
+		}
+		ring.keys[kid] = []byte(secret)
+		if ring.active == "" {
+			ring.active = kid
+		}
+	}
+	if active := os.Getenv("JWT_ACTIVE_KID"); active != "" {
+		if _, ok := ring.keys[active]; !ok {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID %q is not in JWT_KEYS", active)
+		}
+		ring.active = active
+	}
+	return ring, nil
+}
+
+func (k *KeyRing) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// TokenIssuer signs and verifies tokens for one issuer and audience.
+type TokenIssuer struct {
+	keys     *KeyRing
+	issuer   string
+	audience string
+}
+
+func NewTokenIssuer(keys *KeyRing, issuer, audience string) *TokenIssuer {
+	if issuer == "" {
+		issuer = defaultIssuer
+	}
+	if audience == "" {
+		audience = defaultAudience
+	}
+	return &TokenIssuer{keys: keys, issuer: issuer, audience: audience}
+}
+
+func (t *TokenIssuer) Sign(tokenType TokenType, subject, role, sessionID string, ttl time.Duration) (string, *Claims, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    t.issuer,
+			Audience:  jwt.ClaimStrings{t.audience},
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Type:      tokenType,
+		Role:      role,
+		SessionID: sessionID,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = t.keys.active
+	signed, err := token.SignedString(t.keys.keys[t.keys.active])
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, &claims, nil
+}
+
+// Verify checks a token and that its type is one of allowed.
+func (t *TokenIssuer) Verify(tokenString string, allowed ...TokenType) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, t.keys.keyFunc,
+		jwt.WithValidMethods(allowedAlgorithms),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithAudience(t.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" || claims.ID == "" {
+		return nil, fmt.Errorf("token is missing sub or jti")
+	}
+	if !slices.Contains(allowed, claims.Type) {
+		return nil, fmt.Errorf("unexpected token type %q", claims.Type)
+	}
+	return claims, nil
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"revervation/backend/database"
+	"strings"
+	"time"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	errTOTPInvalid       = errors.New("Der Bestätigungscode ist ungültig.")
+	errTOTPNotEnrolled   = errors.New("Die Zwei-Faktor-Authentifizierung ist nicht eingerichtet.")
+	errTOTPAlreadyActive = errors.New("Die Zwei-Faktor-Authentifizierung ist bereits eingerichtet.")
+)
+
+// TwoFactorRepository stores the TOTP secrets and recovery codes of staff
+// accounts and whether a second factor is required for everybody.
+type TwoFactorRepository struct {
+	db     *sql.DB
+	driver database.Driver
+}
+
+func NewTwoFactorRepository(db *sql.DB, driver database.Driver) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db, driver: driver}
+}
+
+func NewTwoFactorStore() *TwoFactorRepository {
+	return NewTwoFactorRepository(database.GetDB(), database.GetDriver())
+}
+
+// Enabled reports whether a staff account has a confirmed authenticator.
+func (r *TwoFactorRepository) Enabled(staffID string) (bool, error) {
+	var enabled bool
+	err := r.db.QueryRow(r.driver.Rebind(`SELECT enabled FROM staff_totp WHERE staff_id = ?`), staffID).Scan(&enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// BeginEnrollment stores a new secret for an account that has no confirmed
+// authenticator yet. It only takes effect once confirmed with a code.
+func (r *TwoFactorRepository) BeginEnrollment(staffID string) (string, error) {
+	enabled, err := r.Enabled(staffID)
+	if err != nil {
+		return "", err
+	}
+	if enabled {
+		return "", errTOTPAlreadyActive
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(r.driver.Rebind(`DELETE FROM staff_totp WHERE staff_id = ?`), staffID); err != nil {
+		return "", err
+	}
+	query := `INSERT INTO staff_totp (staff_id, secret, enabled, last_step, created_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(r.driver.Rebind(query), staffID, secret, false, 0, time.Now()); err != nil {
+		return "", err
+	}
+	return secret, tx.Commit()
+}
+
+// ConfirmEnrollment enables the pending secret once the account proves it
+// can generate codes, and returns a fresh set of recovery codes.
+func (r *TwoFactorRepository) ConfirmEnrollment(staffID, code string) ([]string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	secret, enabled, lastStep, err := r.get(tx, staffID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errTOTPAlreadyActive
+	}
+	step, ok := matchTOTP(secret, code, time.Now(), lastStep)
+	if !ok {
+		return nil, errTOTPInvalid
+	}
+	query := `UPDATE staff_totp SET enabled = ?, last_step = ? WHERE staff_id = ?`
+	if _, err := tx.Exec(r.driver.Rebind(query), true, step, staffID); err != nil {
+		return nil, err
+	}
+	codes, err := r.replaceRecoveryCodes(tx, staffID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// Verify accepts a current authenticator code or an unused recovery code.
+// Either can be used only once.
+func (r *TwoFactorRepository) Verify(staffID, code string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	secret, enabled, lastStep, err := r.get(tx, staffID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errTOTPNotEnrolled
+	}
+	if step, ok := matchTOTP(secret, code, time.Now(), lastStep); ok {
+		// Conditional, so two logins racing with the same code cannot both
+		// pass.
+		query := `UPDATE staff_totp SET last_step = ? WHERE staff_id = ? AND last_step < ?`
+		result, err := tx.Exec(r.driver.Rebind(query), step, staffID, step)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n != 1 {
+			return errTOTPInvalid
+		}
+		return tx.Commit()
+	}
+
+	query := `UPDATE staff_recovery_codes SET used_at = ? WHERE code_hash = ? AND staff_id = ? AND used_at IS NULL`
+	result, err := tx.Exec(r.driver.Rebind(query), time.Now(), hashToken(normalizeRecoveryCode(code)), staffID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 1 {
+		return errTOTPInvalid
+	}
+	return tx.Commit()
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of an account.
+func (r *TwoFactorRepository) RegenerateRecoveryCodes(staffID string) ([]string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	codes, err := r.replaceRecoveryCodes(tx, staffID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// Disable removes the authenticator and recovery codes of an account.
+func (r *TwoFactorRepository) Disable(staffID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"staff_recovery_codes", "staff_totp"} {
+		if _, err := tx.Exec(r.driver.Rebind(`DELETE FROM `+table+` WHERE staff_id = ?`), staffID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Required reports whether every staff login needs a second factor.
+func (r *TwoFactorRepository) Required() (bool, error) {
+	var required bool
+	err := r.db.QueryRow(`SELECT require_totp FROM security_settings WHERE id = 1`).Scan(&required)
+	return required, err
+}
+
+func (r *TwoFactorRepository) SetRequired(required bool) error {
+	_, err := r.db.Exec(r.driver.Rebind(`UPDATE security_settings SET require_totp = ? WHERE id = 1`), required)
+	return err
+}
+
+func (r *TwoFactorRepository) get(q querier, staffID string) (string, bool, int64, error) {
+	var secret string
+	var enabled bool
+	var lastStep int64
+	query := `SELECT secret, enabled, last_step FROM staff_totp WHERE staff_id = ?`
+	err := q.QueryRow(r.driver.Rebind(query), staffID).Scan(&secret, &enabled, &lastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, 0, errTOTPNotEnrolled
+	}
+	return secret, enabled, lastStep, err
+}
+
+func (r *TwoFactorRepository) replaceRecoveryCodes(q querier, staffID string) ([]string, error) {
+	if _, err := q.Exec(r.driver.Rebind(`DELETE FROM staff_recovery_codes WHERE staff_id = ?`), staffID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		query := `INSERT INTO staff_recovery_codes (code_hash, staff_id, created_at) VALUES (?, ?, ?)`
+		if _, err := q.Exec(r.driver.Rebind(query), hashToken(normalizeRecoveryCode(code)), staffID, now); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns a code like 3f9a2-b71c0, easy to write down.
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(buf)
+	return fmt.Sprintf("%s-%s", code[:5], code[5:]), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
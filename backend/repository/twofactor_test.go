@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"revervation/backend/database"
+	"testing"
+	"time"
+)
+
+func TestTwoFactorCodesWorkOnce(t *testing.T) {
+	openTestDB(t, database.DriverSQLite)
+	twoFactor := NewTwoFactorStore()
+	staff := NewStaffStore()
+	if err := staff.EnsureOwner("inhaberin", "supersecret1"); err != nil {
+		t.Fatal(err)
+	}
+	owners, err := staff.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	staffID := owners[0].ID
+
+	secret, err := twoFactor.BeginEnrollment(staffID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := time.Now().Unix() / totpPeriod
+	if _, err := twoFactor.ConfirmEnrollment(staffID, totpCode(key, step)); err != nil {
+		t.Fatal(err)
+	}
+
+	next := totpCode(key, step+1)
+	if err := twoFactor.Verify(staffID, next); err != nil {
+		t.Fatalf("fresh code: %v", err)
+	}
+	if err := twoFactor.Verify(staffID, next); !errors.Is(err, errTOTPInvalid) {
+		t.Errorf("replayed code: got %v, want errTOTPInvalid", err)
+	}
+	if err := twoFactor.Verify(staffID, totpCode(key, step)); !errors.Is(err, errTOTPInvalid) {
+		t.Errorf("code older than the last one: got %v, want errTOTPInvalid", err)
+	}
+}
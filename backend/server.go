@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"revervation/backend/database"
 	"revervation/backend/graph"
+	"revervation/backend/graph/model"
+	"revervation/backend/mailer"
+	"revervation/backend/notifier"
+	"revervation/backend/ratelimit"
 	"revervation/backend/repository"
 	"time"
 
@@ -23,22 +29,23 @@ import (
 )
 
 const defaultPort = "8080"
-const dbPath = "./reservation.db"
+const defaultDBPath = "./reservation.db"
 
-func resetDatabase() {
-	time.Sleep(100 * time.Millisecond)
-
-	if err := os.Remove(dbPath); err != nil && !os.IsNotExist(err) {
-		log.Printf("Error removing DB: %v", err)
-		return
+// databaseConfigFromEnv reads DB_DRIVER (sqlite or postgres) and DATABASE_URL.
+func databaseConfigFromEnv() (database.Driver, string, error) {
+	driver, err := database.ParseDriver(os.Getenv("DB_DRIVER"))
+	if err != nil {
+		return "", "", err
 	}
 
-	if err := database.Init(dbPath); err != nil {
-		log.Printf("Error re-initializing DB: %v", err)
-		return
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		if driver != database.DriverSQLite {
+			return "", "", fmt.Errorf("DATABASE_URL is required for %s", driver)
+		}
+		dsn = defaultDBPath
 	}
-
-	log.Println("Database reset successfully")
+	return driver, dsn, nil
 }
 
 func main() {
@@ -51,21 +58,148 @@ func main() {
 		port = defaultPort
 	}
 
-	if err := database.Init(dbPath); err != nil {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	dbDriver, dsn, err := databaseConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid database config: %v", err)
+	}
+
+	if err := database.Init(dbDriver, dsn); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer database.Close()
 
+	smsConfig, err := notifier.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid SMS config: %v", err)
+	}
+	channels := []model.NotificationChannel{model.NotificationChannelEmail}
+	if smsConfig.Enabled() {
+		channels = append(channels, model.NotificationChannelSms)
+	}
+
+	store, err := repository.NewReservationStore(channels)
+	if err != nil {
+		log.Fatalf("Failed to create reservation store: %v", err)
+	}
+	staff := repository.NewStaffStore()
+	if err := staff.EnsureOwner(os.Getenv("ADMIN_USERNAME"), os.Getenv("ADMIN_PASSWORD")); err != nil {
+		log.Fatalf("Failed to create initial owner account: %v", err)
+	}
+	authService := repository.NewAuthService(store, staff, repository.NewSessionStore(), repository.NewGuestLinkStore(), repository.NewTwoFactorStore(), repository.NewAPIKeyStore())
+
+	rateLimitConfig, err := ratelimit.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid rate limit config: %v", err)
+	}
+	limiter := ratelimit.NewLimiter(rateLimitConfig)
+
+	mailConfig, err := mailer.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid mail config: %v", err)
+	}
+	mailTransport, err := mailer.NewTransport(mailConfig)
+	if err != nil {
+		log.Fatalf("Invalid mail config: %v", err)
+	}
+	mailTemplates, err := mailer.NewTemplates(mailConfig.TemplateDir)
+	if err != nil {
+		log.Fatalf("Invalid mail templates: %v", err)
+	}
+	hours := repository.NewOpeningHoursStore()
+	mail := mailer.NewMailer(mailConfig, mailTransport, mailTemplates, hours)
+
+	notifyChannels := []notifier.Channel{notifier.NewEmailChannel(mail)}
+	if smsConfig.Enabled() {
+		smsProvider, err := notifier.NewProvider(smsConfig)
+		if err != nil {
+			log.Fatalf("Invalid SMS config: %v", err)
+		}
+		notifyChannels = append(notifyChannels, notifier.NewSMSChannel(smsConfig, smsProvider, hours))
+	}
+	notify := notifier.NewNotifier(notifyChannels...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if mailConfig.TemplateDir != "" {
+		go mailTemplates.Watch(ctx, 2*time.Second)
+	}
+
+	outboxConfig, err := repository.OutboxConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid outbox config: %v", err)
+	}
+	outbox := repository.NewOutboxService(outboxConfig, store, notify, mail)
+	go outbox.Run(ctx)
+
+	reminderConfig, err := repository.ReminderConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid reminder config: %v", err)
+	}
+	reminders := repository.NewReminderService(reminderConfig, store, mail)
+
+	retentionConfig, err := repository.RetentionConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid retention config: %v", err)
+	}
+	retention := repository.NewRetentionService(retentionConfig)
+
 	c := cron.New()
-	_, err := c.AddFunc("0 8 * * *", resetDatabase)
+	_, err = c.AddFunc(retentionConfig.Schedule, func() {
+		moved, err := retention.Run(time.Now())
+		if err != nil {
+			log.Printf("Retention run failed: %v", err)
+			return
+		}
+		log.Printf("Retention run archived %d reservations", moved)
+	})
+	if err != nil {
+		log.Fatalf("Failed to schedule cron: %v", err)
+	}
+	_, err = c.AddFunc(reminderConfig.Schedule, func() {
+		if _, err := reminders.Run(time.Now()); err != nil {
+			log.Printf("Reminder run failed: %v", err)
+		}
+	})
+	if err != nil {
+		log.Fatalf("Failed to schedule cron: %v", err)
+	}
+	_, err = c.AddFunc("@hourly", func() {
+		if _, err := authService.DeleteExpiredSessions(time.Now()); err != nil {
+			log.Printf("Session cleanup failed: %v", err)
+		}
+		if _, err := authService.DeleteExpiredGuestLinks(time.Now()); err != nil {
+			log.Printf("Login link cleanup failed: %v", err)
+		}
+		limiter.Sweep(time.Now())
+		if _, err := outbox.DeleteSent(time.Now()); err != nil {
+			log.Printf("Outbox cleanup failed: %v", err)
+		}
+		if _, err := reminders.DeleteExpired(time.Now()); err != nil {
+			log.Printf("Reminder cleanup failed: %v", err)
+		}
+	})
 	if err != nil {
 		log.Fatalf("Failed to schedule cron: %v", err)
 	}
 	c.Start()
 	defer c.Stop()
 
-	resolver := graph.NewResolver()
-	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	resolver := graph.NewResolver(store, authService, hours, staff, limiter, mail, outbox, reminders)
+	schema := graph.NewExecutableSchema(graph.Config{
+		Resolvers:  resolver,
+		Directives: graph.DirectiveRoot{Auth: graph.Auth},
+	})
+	if err := graph.ValidateAuthRules(schema.Schema()); err != nil {
+		log.Fatalf("Invalid schema: %v", err)
+	}
+	srv := handler.New(schema)
 
 	srv.AddTransport(&transport.Websocket{
 		Upgrader: websocket.Upgrader{
@@ -76,6 +210,8 @@ func main() {
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
 
+	srv.AroundRootFields(graph.RecordAPIKeyUsage(authService))
+	srv.SetErrorPresenter(graph.ErrorPresenter)
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
 	srv.Use(extension.Introspection{})
 	srv.Use(extension.AutomaticPersistedQuery{
@@ -85,18 +221,20 @@ func main() {
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins:   []string{"https://reserve.thinis.de", "http://localhost:3000"},
 		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Api-Key"},
 		AllowCredentials: true,
 		Debug:            false,
 	}).Handler
 
 	router := chi.NewRouter()
+	router.Use(limiter.ClientAddr)
 	router.Use(func(next http.Handler) http.Handler {
 		return corsMiddleware(next)
 	})
+	router.Use(limiter.Middleware)
 
-	router.Handle("/", repository.Middleware()(playground.Handler("Reservation", "/query")))
-	router.Handle("/query", repository.Middleware()(srv))
+	router.Handle("/", repository.Middleware(authService)(playground.Handler("Reservation", "/query")))
+	router.Handle("/query", repository.Middleware(authService)(srv))
 
 	// log.Printf("Server running on http://localhost:%s/ (GraphQL Playground at /)", port)
 	log.Fatal(http.ListenAndServe(":"+port, router))
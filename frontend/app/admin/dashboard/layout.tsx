@@ -2,6 +2,7 @@
 
 import { ReactNode, useEffect, useState } from "react";
 import { useRouter } from "next/navigation";
+import { hasSession } from "@/lib/session";
 
 type Props = {
   children?: ReactNode;
@@ -12,22 +13,10 @@ export default function AdminDashboardLayout({ children }: Props) {
   const [isAuthenticated, setIsAuthenticated] = useState(false);
 
   useEffect(() => {
-    const tokenStr = localStorage.getItem("adminToken");
-    if (!tokenStr) {
-      router.replace("/admin");
-      return;
-    }
-
-    try {
-      const { token, expire } = JSON.parse(tokenStr) as { token: string; expire: number };
-      if (!token || !expire || Date.now() > expire) {
-        localStorage.removeItem("adminToken");
-        router.replace("/admin");
-      } else {
-        setIsAuthenticated(true);
-      }
-    } catch {
-      localStorage.removeItem("adminToken");
+    // An expired access token is fine as long as the session can be renewed.
+    if (hasSession("admin")) {
+      setIsAuthenticated(true);
+    } else {
       router.replace("/admin");
     }
   }, [router]);
@@ -2,6 +2,7 @@
 
 import { useEffect, useState } from "react";
 import { useRouter } from "next/navigation";
+import { hasSession } from "@/lib/session";
 
 export default function AdminLayout({
   children,
@@ -12,31 +13,12 @@ export default function AdminLayout({
   const [checkingAuth, setCheckingAuth] = useState(true);
 
   useEffect(() => {
-    const tokenStr = localStorage.getItem("adminToken");
-    if (!tokenStr) {
-      setCheckingAuth(false); // no token → stop checking → render login
-      return;
-    }
-
-    try {
-      const { token, expire } = JSON.parse(tokenStr) as {
-        token: string;
-        expire: number;
-      };
-
-      if (!token || !expire || Date.now() > expire) {
-        localStorage.removeItem("adminToken");
-        setCheckingAuth(false); // expired → stop checking → render login
-      } else {
-        // valid token → redirect to dashboard
-        router.replace("/admin/dashboard");
-        // also stop showing loading, otherwise it'll stay stuck
-        setCheckingAuth(false);
-      }
-    } catch {
-      localStorage.removeItem("adminToken");
-      setCheckingAuth(false);
+    // signed in, or able to renew the session → redirect to dashboard
+    if (hasSession("admin")) {
+      router.replace("/admin/dashboard");
     }
+    // stop showing loading, otherwise it'll stay stuck
+    setCheckingAuth(false);
   }, [router]);
 
   if (checkingAuth) {
@@ -0,0 +1,85 @@
+"use client";
+
+import { useState } from "react";
+import { useMutation } from "@apollo/client/react";
+import { useSearchParams } from "next/navigation";
+import { ACCEPT_STAFF_INVITE } from "@/graphql/mutations";
+import { LoginResult } from "@/lib/modelTypes";
+import { storeSession } from "@/lib/session";
+
+// Invites and password resets both end here with the token from the mail.
+export default function SetPasswordPage() {
+  const token = useSearchParams().get("token");
+  const [password, setPassword] = useState("");
+  const [repeat, setRepeat] = useState("");
+  const [error, setError] = useState("");
+
+  const [acceptInvite, { loading }] = useMutation<{ acceptStaffInvite: LoginResult }>(ACCEPT_STAFF_INVITE);
+
+  if (!token) {
+    return <div className="flex items-center justify-center min-h-screen">Ungültiger Link</div>;
+  }
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError("");
+
+    if (password !== repeat) {
+      setError("Die Passwörter stimmen nicht überein.");
+      return;
+    }
+
+    try {
+      const { data } = await acceptInvite({ variables: { token, password } });
+      if (data?.acceptStaffInvite.auth) {
+        storeSession("admin", data.acceptStaffInvite.auth);
+        window.location.href = "/admin/dashboard";
+      } else {
+        // Two-factor authentication is completed in the regular login.
+        window.location.href = "/admin";
+      }
+    } catch (err: any) {
+      setError(err.message || "Das Passwort konnte nicht gesetzt werden.");
+    }
+  };
+
+  return (
+    <main className="flex flex-col items-center justify-center min-h-screen bg-base-100 p-4">
+      <form
+        className="w-full max-w-md p-8 bg-base-200 border border-base-300 rounded-box shadow-md"
+        onSubmit={handleSubmit}
+      >
+        <h2 className="text-2xl font-bold mb-6 text-center">Passwort festlegen</h2>
+
+        {error && <p className="text-red-500 mb-4">{error}</p>}
+        {loading && <p className="mb-4">Bitte warten…</p>}
+
+        <label className="label">Neues Passwort</label>
+        <input
+          type="password"
+          autoComplete="new-password"
+          className="input w-full mb-4"
+          minLength={10}
+          value={password}
+          onChange={(e) => setPassword(e.target.value)}
+          required
+        />
+
+        <label className="label">Passwort wiederholen</label>
+        <input
+          type="password"
+          autoComplete="new-password"
+          className="input w-full mb-6"
+          minLength={10}
+          value={repeat}
+          onChange={(e) => setRepeat(e.target.value)}
+          required
+        />
+
+        <button type="submit" className="btn btn-neutral w-full" disabled={loading}>
+          Passwort speichern
+        </button>
+      </form>
+    </main>
+  );
+}
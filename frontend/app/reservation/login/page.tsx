@@ -0,0 +1,64 @@
+"use client";
+
+import Link from "next/link";
+import { useGuestLoginLink } from "@/hooks/useGuestLoginLink";
+import ReservationStatusBadge from "@/components/ReservationStatusBadge";
+import { formatDateTime } from "@/lib/utils";
+
+export default function GuestLoginPage() {
+  const { linkToken, reservations, error, loading, handleLogin } = useGuestLoginLink();
+
+  if (!linkToken) {
+    return <div className="flex items-center justify-center min-h-screen">Ungültiger Link</div>;
+  }
+
+  if (!reservations) {
+    return (
+      <div className="flex items-center justify-center min-h-screen bg-base-100">
+        <div className="card w-full max-w-md shadow-2xl bg-base-200">
+          <div className="card-body">
+            <h2 className="card-title text-2xl">Meine Reservierungen</h2>
+            <p className="text-sm opacity-80">Melden Sie sich an, um Ihre anstehenden Reservierungen zu sehen.</p>
+            {error && (
+              <div className="alert alert-error mt-4">
+                {error}
+                <Link href="/" className="btn btn-sm">Zur Startseite</Link>
+              </div>
+            )}
+            <button className="btn btn-primary mt-4" onClick={handleLogin} disabled={loading}>
+              {loading ? <span className="loading loading-spinner"></span> : "Anmelden"}
+            </button>
+          </div>
+        </div>
+      </div>
+    );
+  }
+
+  return (
+    <main className="p-6 min-h-screen bg-base-100">
+      <h1 className="text-3xl font-bold mb-8 text-center">Meine Reservierungen</h1>
+
+      <div className="max-w-2xl mx-auto space-y-4">
+        {reservations.length === 0 && (
+          <p className="text-center opacity-80">Sie haben keine anstehenden Reservierungen.</p>
+        )}
+        {reservations.map((reservation) => (
+          <div key={reservation.id} className="card bg-base-200 shadow-xl">
+            <div className="card-body flex-row items-center justify-between gap-4">
+              <div>
+                <p className="font-semibold">{formatDateTime(reservation.reserveAt)}</p>
+                <p className="text-sm opacity-80">{reservation.amount} Personen · {reservation.lastName}</p>
+              </div>
+              <div className="flex items-center gap-4">
+                <ReservationStatusBadge status={reservation.status} />
+                <Link href={`/reservation?id=${reservation.id}`} className="btn btn-outline btn-sm">
+                  Öffnen
+                </Link>
+              </div>
+            </div>
+          </div>
+        ))}
+      </div>
+    </main>
+  );
+}
@@ -5,6 +5,7 @@ import GuestAuthModal from "@/components/GuestAuthModal";
 import EditableField from "@/components/EditableField";
 import ReservationStatusBadge from "@/components/ReservationStatusBadge";
 import NotificationToast from "@/components/NotificationToast";
+import { clearSession } from "@/lib/session";
 
 export default function GuestReservationPage() {
   const {
@@ -82,7 +83,7 @@ export default function GuestReservationPage() {
             </div>
 
             <div className="text-center text-sm opacity-70 mt-6">
-              <button className="link" onClick={() => { localStorage.removeItem("userToken"); setShowAuthModal(true); }}>
+              <button className="link" onClick={() => { clearSession("guest"); setShowAuthModal(true); }}>
                 Abmelden
               </button>
             </div>
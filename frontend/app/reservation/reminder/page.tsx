@@ -0,0 +1,54 @@
+"use client";
+
+import Link from "next/link";
+import { useReminderResponse } from "@/hooks/useReminderResponse";
+import { formatDateTime } from "@/lib/utils";
+import { ReservationStatus } from "@/lib/modelTypes";
+
+export default function ReminderResponsePage() {
+  const { reminderToken, action, reservation, error, loading, handleRespond } = useReminderResponse();
+
+  if (!reminderToken) {
+    return <div className="flex items-center justify-center min-h-screen">Ungültiger Link</div>;
+  }
+
+  if (reservation) {
+    const canceled = reservation.status === ReservationStatus.CANCELED;
+    return (
+      <div className="flex flex-col items-center justify-center min-h-screen gap-4 p-6 text-center">
+        <h1 className="text-3xl font-bold">{canceled ? "Reservierung storniert" : "Vielen Dank!"}</h1>
+        <p>
+          {canceled
+            ? `Ihre Reservierung am ${formatDateTime(reservation.reserveAt)} wurde storniert.`
+            : `Wir freuen uns auf Ihren Besuch am ${formatDateTime(reservation.reserveAt)}.`}
+        </p>
+        <Link href="/" className="btn btn-outline">Zur Startseite</Link>
+      </div>
+    );
+  }
+
+  return (
+    <div className="flex items-center justify-center min-h-screen bg-base-100">
+      <div className="card w-full max-w-md shadow-2xl bg-base-200">
+        <div className="card-body">
+          <h2 className="card-title text-2xl">
+            {action === "cancel" ? "Reservierung stornieren" : "Besuch bestätigen"}
+          </h2>
+          <p className="text-sm opacity-80">
+            {action === "cancel"
+              ? "Möchten Sie Ihre Reservierung wirklich stornieren?"
+              : "Bitte bestätigen Sie, dass Sie wie geplant kommen."}
+          </p>
+          {error && <div className="alert alert-error mt-4">{error}</div>}
+          <button
+            className={`btn ${action === "cancel" ? "btn-error" : "btn-primary"} mt-4`}
+            onClick={() => handleRespond(action === "confirm")}
+            disabled={loading}
+          >
+            {action === "cancel" ? "Ja, stornieren" : "Ja, ich komme"}
+          </button>
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -32,6 +32,9 @@ export default function DashboardStatsGrid({ info }: Props) {
       <StatCard title="Gesamtreservierungen" value={info.totalReservation} href="/admin/dashboard/total" className="bg-base-200" />
       <StatCard title="Gesamtpersonen" value={info.totalPerson} href="/admin/dashboard" className="bg-base-200" />
       <StatCard title="Große Tische (≥5)" value={info.totalBigReservation} href="/admin/dashboard/big-tables" className="bg-base-200" />
+      <StatCard title="Gäste am Tisch" value={info.seatedCovers} href="/admin/dashboard" className="bg-base-200" />
+      <StatCard title="Nicht erschienen" value={info.totalNoShowReservation} href="/admin/dashboard" className="bg-base-200" />
+      <StatCard title="No-Show-Quote (%)" value={Math.round(info.noShowRate * 100)} href="/admin/dashboard" className="bg-base-200" />
     </div>
   );
 }
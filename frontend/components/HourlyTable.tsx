@@ -3,6 +3,7 @@ import { useLazyQuery } from "@apollo/client/react";
 import { formatTime, formatLocalDateTime } from "@/lib/utils";
 import { GET_ALL_RESERVATION_WITH_FILTER } from "@/graphql/queries";
 import { Reservation } from "@/lib/modelTypes";
+import { adminContext, hasSession } from "@/lib/session";
 import ReservationList from "@/components/ReservationList";
 import NotificationToast from "@/components/NotificationToast";
 
@@ -12,6 +13,7 @@ interface HourRow {
   totalReservation: number;
   totalBigReservation: number;
   totalPerson: number;
+  totalSeatedPerson: number;
 }
 
 interface Props {
@@ -26,7 +28,7 @@ export default function HourlyTable({ hours, onConfirm, onDecline, onOpen, onCha
   const [now, setNow] = useState(new Date());
   const [expandedHourIndex, setExpandedHourIndex] = useState<number | null>(null);
   const [expandedReservationId, setExpandedReservationId] = useState<string | null>(null);
-  const [token, setToken] = useState<string | null>(null);
+  const [signedIn, setSignedIn] = useState(false);
   const [errorMessage, setErrorMessage] = useState<string | null>(null);
 
   const [fetchReservations, { data, loading, error, refetch }] = useLazyQuery<
@@ -44,17 +46,11 @@ export default function HourlyTable({ hours, onConfirm, onDecline, onOpen, onCha
   }, []);
 
   useEffect(() => {
-    const t = localStorage.getItem("adminToken");
-    if (t) {
-      try {
-        const parsed = JSON.parse(t) as { token: string };
-        setToken(parsed.token);
-      } catch {}
-    }
+    setSignedIn(hasSession("admin"));
   }, []);
 
   useEffect(() => {
-    if (expandedHourIndex !== null && token) {
+    if (expandedHourIndex !== null && signedIn) {
       const hour = hours[expandedHourIndex];
 
       const dateFrom = new Date(hour.startsAt);
@@ -69,10 +65,10 @@ export default function HourlyTable({ hours, onConfirm, onDecline, onOpen, onCha
             dateFrom: formatLocalDateTime(dateFrom),
             dateTo: formatLocalDateTime(dateTo),          },
         },
-        context: { headers: { Authorization: `Bearer ${token}` } },
+        context: adminContext,
       }).catch(() => setErrorMessage("Fehler beim Laden der Reservierungen"));
     }
-  }, [expandedHourIndex, token, hours, fetchReservations]);
+  }, [expandedHourIndex, signedIn, hours, fetchReservations]);
   const reservations: Reservation[] = data?.getAllReservationWithFilter || [];
   const sortedReservations = [...reservations].sort((a, b) => b.amount - a.amount);
 
@@ -95,6 +91,7 @@ export default function HourlyTable({ hours, onConfirm, onDecline, onOpen, onCha
             <th>Reservierungen</th>
             <th>Große Tische</th>
             <th>Personen</th>
+            <th>Am Tisch</th>
           </tr>
         </thead>
         <tbody>
@@ -126,10 +123,11 @@ export default function HourlyTable({ hours, onConfirm, onDecline, onOpen, onCha
                   <td>{hour.totalReservation}</td>
                   <td>{hour.totalBigReservation}</td>
                   <td>{hour.totalPerson}</td>
+                  <td>{hour.totalSeatedPerson}</td>
                 </tr>
                 {isExpanded && (
                   <tr>
-                    <td colSpan={4} className="p-0">
+                    <td colSpan={5} className="p-0">
                       <div className="bg-base-300">
                         {loading ? (
                           <div className="flex justify-center py-4">
@@ -57,7 +57,7 @@ export default function ReservationCard({
             <div className="flex flex-wrap gap-2">
               <button className="btn btn-warning btn-sm" onClick={onOpen} disabled={reservation.status === "OPEN"}>Offen</button>
               <button className="btn btn-success btn-sm" onClick={onConfirm} disabled={reservation.status === "CONFIRMED"}>Bestätigen</button>
-              <button className="btn btn-error btn-sm" onClick={onDecline} disabled={["CANCELED", "DECLINED"].includes(reservation.status)}>Ablehnen</button>
+              <button className="btn btn-error btn-sm" onClick={onDecline} disabled={["CANCELED", "DECLINED", "SEATED", "COMPLETED", "NO_SHOW"].includes(reservation.status)}>Ablehnen</button>
               <button className="btn btn-outline btn-sm" onClick={onChangeTime}>Zeit ändern</button>
             </div>
           </div>
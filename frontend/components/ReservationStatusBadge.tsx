@@ -9,6 +9,9 @@ const statusMap = {
   [ReservationStatus.CONFIRMED]: { label: "Bestätigt", color: "badge-success" },
   [ReservationStatus.CANCELED]: { label: "Storniert", color: "badge-error" },
   [ReservationStatus.DECLINED]: { label: "Abgelehnt", color: "badge-error" },
+  [ReservationStatus.SEATED]: { label: "Platziert", color: "badge-info" },
+  [ReservationStatus.COMPLETED]: { label: "Abgeschlossen", color: "badge-neutral" },
+  [ReservationStatus.NO_SHOW]: { label: "Nicht erschienen", color: "badge-error" },
 };
 
 export default function ReservationStatusBadge({ status }: Props) {
@@ -2,14 +2,20 @@
 
 import { useState } from "react";
 import { useMutation } from "@apollo/client/react";
-import { LOGIN_ADMIN } from "@/graphql/mutations"; // your GraphQL mutation
+import { LOGIN_ADMIN, VERIFY_TOTP_LOGIN } from "@/graphql/mutations"; // your GraphQL mutation
+import { LoginResult, TotpChallenge, TotpLoginResult } from "@/lib/modelTypes";
+import { storeSession } from "@/lib/session";
 
 export default function AdminLoginForm() {
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
   const [error, setError] = useState("");
+  const [challenge, setChallenge] = useState<TotpChallenge | null>(null);
+  const [code, setCode] = useState("");
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
 
-  const [loginAdmin, { loading }] = useMutation<{login: string}>(LOGIN_ADMIN);
+  const [loginAdmin, { loading }] = useMutation<{login: LoginResult}>(LOGIN_ADMIN);
+  const [verifyTotp, { loading: verifying }] = useMutation<{verifyTotpLogin: TotpLoginResult}>(VERIFY_TOTP_LOGIN);
 
   const handleLogin = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -25,16 +31,13 @@ export default function AdminLoginForm() {
         variables: { username, password },
       });
 
-      if (data?.login) {
-        const token = data.login;
-        const expireTime = Date.now() + 16 * 60 * 60 * 1000; // 16 hours in ms
-        localStorage.setItem(
-          "adminToken",
-          JSON.stringify({ token, expire: expireTime })
-        );
+      if (data?.login.auth) {
+        storeSession("admin", data.login.auth);
 
         // Optionally redirect to admin dashboard
         window.location.href = "/admin/dashboard";
+      } else if (data?.login.challenge) {
+        setChallenge(data.login.challenge);
       } else {
         setError("Ungültiger Benutzername oder Passwort.");
       }
@@ -43,6 +46,93 @@ export default function AdminLoginForm() {
     }
   };
 
+  const handleVerify = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError("");
+
+    try {
+      const { data } = await verifyTotp({
+        variables: { challenge: challenge?.token, code },
+      });
+      if (!data) return;
+
+      storeSession("admin", data.verifyTotpLogin.auth);
+      if (data.verifyTotpLogin.recoveryCodes.length > 0) {
+        // Show the codes once before continuing to the dashboard.
+        setRecoveryCodes(data.verifyTotpLogin.recoveryCodes);
+        return;
+      }
+      window.location.href = "/admin/dashboard";
+    } catch (err: any) {
+      setError(err.message || "Bestätigung fehlgeschlagen.");
+    }
+  };
+
+  if (recoveryCodes.length > 0) {
+    return (
+      <div className="max-w-md mx-auto mt-12 p-8 bg-base-200 border border-base-300 rounded-box shadow-md">
+        <h2 className="text-2xl font-bold mb-4 text-center">Wiederherstellungscodes</h2>
+        <p className="mb-4">
+          Bewahren Sie diese Codes sicher auf. Jeder Code kann einmal statt eines
+          Bestätigungscodes verwendet werden.
+        </p>
+        <ul className="font-mono grid grid-cols-2 gap-2 mb-6">
+          {recoveryCodes.map((c) => (
+            <li key={c}>{c}</li>
+          ))}
+        </ul>
+        <button
+          className="btn btn-neutral w-full"
+          onClick={() => (window.location.href = "/admin/dashboard")}
+        >
+          Weiter
+        </button>
+      </div>
+    );
+  }
+
+  if (challenge) {
+    return (
+      <form
+        className="max-w-md mx-auto mt-12 p-8 bg-base-200 border border-base-300 rounded-box shadow-md"
+        onSubmit={handleVerify}
+      >
+        <h2 className="text-2xl font-bold mb-6 text-center">Zwei-Faktor-Anmeldung</h2>
+
+        {error && <p className="text-red-500 mb-4">{error}</p>}
+        {verifying && <p className="mb-4">Bitte warten…</p>}
+
+        {challenge.enrollment && (
+          <div className="mb-6">
+            <p className="mb-2">
+              Die Zwei-Faktor-Authentifizierung ist vorgeschrieben. Fügen Sie
+              diesen Schlüssel in Ihrer Authenticator-App hinzu:
+            </p>
+            <p className="font-mono break-all mb-2">{challenge.enrollment.secret}</p>
+            <a className="link" href={challenge.enrollment.provisioningUri}>
+              In Authenticator-App öffnen
+            </a>
+          </div>
+        )}
+
+        <label className="label">Bestätigungscode oder Wiederherstellungscode</label>
+        <input
+          type="text"
+          autoComplete="one-time-code"
+          className="input w-full mb-6"
+          placeholder="123456"
+          value={code}
+          onChange={(e) => setCode(e.target.value)}
+          required
+        />
+
+        <button type="submit" className="btn btn-neutral w-full">
+          Bestätigen
+        </button>
+      </form>
+    );
+  }
+
   return (
     <form
       className="max-w-md mx-auto mt-12 p-8 bg-base-200 border border-base-300 rounded-box shadow-md"
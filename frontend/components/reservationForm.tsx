@@ -2,9 +2,11 @@
 
 import { useState } from "react";
 import { useRouter } from "next/navigation";
-import { useMutation } from "@apollo/client/react";
+import { useMutation, useQuery } from "@apollo/client/react";
 import { CREATE_RESERVATION } from "@/graphql/mutations";
-import { LoginWithReservationResponse } from "@/lib/modelTypes"
+import { GET_AVAILABILITY } from "@/graphql/queries";
+import { AvailableSlot, LoginWithReservationResponse } from "@/lib/modelTypes"
+import { storeSession } from "@/lib/session";
 
 export default function ReservationForm() {
   const [phase, setPhase] = useState(1);
@@ -23,8 +25,15 @@ export default function ReservationForm() {
   const [createReservation, { loading, error }] = useMutation<{createReservation: LoginWithReservationResponse}>(CREATE_RESERVATION);
   const today = new Date().toISOString().slice(0, 10);
 
+  const { data: availabilityData, loading: availabilityLoading } = useQuery<{ availability: AvailableSlot[] }>(GET_AVAILABILITY, {
+    variables: { date: new Date(`${date}T12:00`).toISOString(), partySize: persons },
+    skip: !date,
+    fetchPolicy: "network-only",
+  });
+  const slotTimes = (availabilityData?.availability ?? []).map((slot) => new Date(slot.startsAt).toTimeString().slice(0, 5));
+
   const handleNext = async () => {
-    if (phase === 1 && (!date || !time || persons < 1)) return;
+    if (phase === 1 && (!date || !time || persons < 1 || !slotTimes.includes(time))) return;
     if (phase === 2 && (!email || !phone || phone.length < 9 || !lastName)) return;
 
     if (phase === 2) {
@@ -44,7 +53,7 @@ export default function ReservationForm() {
         const id = data?.createReservation?.reservation?.id;
         const token = data?.createReservation?.token;
         if (id && token) {
-          localStorage.setItem("userToken", JSON.stringify({ token, expire: Date.now() + 86400000 }));
+          storeSession("guest", data.createReservation);
           setSuccess({ id, token });
         }
       } catch (e) {
@@ -81,8 +90,15 @@ export default function ReservationForm() {
               <div className="form-control w-full">
                 <label className="label"><span className="label-text font-medium">Uhrzeit</span></label>
                 <br/>
-                <input type="time" className="input input-bordered input-lg rounded-xl w-full" value={time}
-                       onChange={(e) => setTime(e.target.value)} />
+                <select className="select select-bordered select-lg rounded-xl w-full" value={time}
+                        onChange={(e) => setTime(e.target.value)} disabled={availabilityLoading || slotTimes.length === 0}>
+                  <option value="" disabled>
+                    {availabilityLoading ? "Lade freie Zeiten…" : slotTimes.length === 0 ? "Keine freien Zeiten an diesem Tag" : "Uhrzeit wählen"}
+                  </option>
+                  {slotTimes.map((slotTime) => (
+                    <option key={slotTime} value={slotTime}>{slotTime} Uhr</option>
+                  ))}
+                </select>
               </div>
 
               <div className="form-control w-ful">
@@ -1,7 +1,7 @@
 import { gql } from "@apollo/client"
 
 export const CREATE_RESERVATION = gql`
-mutation CreateReservation($firstName: String, $lastName: String!, $phoneNumber: String!, $email: String!, $amount: Int!, $reserveAt: Time!, $notes: String!) {
+mutation CreateReservation($firstName: String, $lastName: String!, $phoneNumber: String!, $email: String!, $amount: Int!, $reserveAt: Time!, $notes: String!, $notificationChannels: [NotificationChannel!]) {
   createReservation(input: {
     firstName: $firstName 
     lastName: $lastName 
@@ -10,8 +10,11 @@ mutation CreateReservation($firstName: String, $lastName: String!, $phoneNumber:
     amount: $amount 
     reserveAt: $reserveAt 
     notes: $notes 
+    notificationChannels: $notificationChannels
   }) {
     token
+    refreshToken
+    expiresAt
     reservation {
       id
       firstName
@@ -28,7 +31,34 @@ mutation CreateReservation($firstName: String, $lastName: String!, $phoneNumber:
 
 export const LOGIN_ADMIN = gql`
   mutation Login($username: String!, $password: String!) {
-    login(username: $username, password: $password)
+    login(username: $username, password: $password) {
+      auth {
+        token
+        refreshToken
+        expiresAt
+      }
+      challenge {
+        token
+        expiresAt
+        enrollment {
+          secret
+          provisioningUri
+        }
+      }
+    }
+  }
+`
+
+export const VERIFY_TOTP_LOGIN = gql`
+  mutation VerifyTotpLogin($challenge: String!, $code: String!) {
+    verifyTotpLogin(challenge: $challenge, code: $code) {
+      auth {
+        token
+        refreshToken
+        expiresAt
+      }
+      recoveryCodes
+    }
   }
 `
 
@@ -70,6 +100,8 @@ export const LOGIN_WITH_RESERVATION = gql`
   mutation LoginWithReservation($id: ID!, $lastName: String!) {
     loginWithReservation(id: $id, lastName: $lastName) {
       token
+      refreshToken
+      expiresAt
       reservation {
         id
         firstName
@@ -86,6 +118,34 @@ export const LOGIN_WITH_RESERVATION = gql`
   }
 `;
 
+export const REQUEST_GUEST_LOGIN_LINK = gql`
+  mutation RequestGuestLoginLink($email: String!) {
+    requestGuestLoginLink(email: $email)
+  }
+`;
+
+export const REDEEM_GUEST_LOGIN_LINK = gql`
+  mutation RedeemGuestLoginLink($token: String!) {
+    redeemGuestLoginLink(token: $token) {
+      token
+      refreshToken
+      expiresAt
+      reservations {
+        id
+        firstName
+        lastName
+        phoneNumber
+        email
+        amount
+        createdAt
+        reserveAt
+        status
+        notes
+      }
+    }
+  }
+`;
+
 export const CANCEL_RESERVATION = gql`
   mutation CancelReservation($id: ID!) {
     cancelReservation(id: $id) {
@@ -103,3 +163,194 @@ export const  OPEN_RESERVATION = gql`
     }
   }
 `
+
+export const SEAT_RESERVATION = gql`
+  mutation SeatReservation($id: ID!) {
+    seatReservation(id: $id) {
+      id
+      status
+    }
+  }
+`;
+
+export const COMPLETE_RESERVATION = gql`
+  mutation CompleteReservation($id: ID!) {
+    completeReservation(id: $id) {
+      id
+      status
+    }
+  }
+`;
+
+export const MARK_RESERVATION_NO_SHOW = gql`
+  mutation MarkReservationNoShow($id: ID!) {
+    markReservationNoShow(id: $id) {
+      id
+      status
+    }
+  }
+`;
+
+export const INVITE_STAFF = gql`
+  mutation InviteStaff($input: InviteStaff!) {
+    inviteStaff(input: $input) {
+      inviteToken
+      expiresAt
+      staff {
+        id
+        username
+        role
+      }
+    }
+  }
+`;
+
+export const ACCEPT_STAFF_INVITE = gql`
+  mutation AcceptStaffInvite($token: String!, $password: String!) {
+    acceptStaffInvite(token: $token, password: $password) {
+      auth {
+        token
+        refreshToken
+        expiresAt
+      }
+      challenge {
+        token
+        expiresAt
+        enrollment {
+          secret
+          provisioningUri
+        }
+      }
+    }
+  }
+`;
+
+export const BEGIN_TOTP_ENROLLMENT = gql`
+  mutation BeginTotpEnrollment {
+    beginTotpEnrollment {
+      secret
+      provisioningUri
+    }
+  }
+`;
+
+export const CONFIRM_TOTP_ENROLLMENT = gql`
+  mutation ConfirmTotpEnrollment($code: String!) {
+    confirmTotpEnrollment(code: $code)
+  }
+`;
+
+export const REGENERATE_RECOVERY_CODES = gql`
+  mutation RegenerateRecoveryCodes($code: String!) {
+    regenerateRecoveryCodes(code: $code)
+  }
+`;
+
+export const DISABLE_TOTP = gql`
+  mutation DisableTotp($code: String!) {
+    disableTotp(code: $code)
+  }
+`;
+
+export const RESET_STAFF_TOTP = gql`
+  mutation ResetStaffTotp($id: ID!) {
+    resetStaffTotp(id: $id) {
+      id
+      totpEnabled
+    }
+  }
+`;
+
+export const SET_TOTP_REQUIRED = gql`
+  mutation SetTotpRequired($required: Boolean!) {
+    setTotpRequired(required: $required)
+  }
+`;
+
+export const RESET_STAFF_PASSWORD = gql`
+  mutation ResetStaffPassword($id: ID!) {
+    resetStaffPassword(id: $id) {
+      id
+      pendingInvite
+    }
+  }
+`;
+
+export const DISABLE_STAFF = gql`
+  mutation DisableStaff($id: ID!) {
+    disableStaff(id: $id) {
+      id
+      disabled
+    }
+  }
+`;
+
+export const REFRESH_TOKEN = gql`
+  mutation RefreshToken($token: String!) {
+    refreshToken(token: $token) {
+      token
+      refreshToken
+      expiresAt
+    }
+  }
+`;
+
+export const REVOKE_SESSION = gql`
+  mutation RevokeSession($id: ID!) {
+    revokeSession(id: $id)
+  }
+`;
+
+export const CREATE_API_KEY = gql`
+  mutation CreateApiKey($input: NewApiKey!) {
+    createApiKey(input: $input) {
+      key
+      apiKey {
+        id
+        name
+        prefix
+        scopes
+        createdAt
+      }
+    }
+  }
+`;
+
+export const REVOKE_API_KEY = gql`
+  mutation RevokeApiKey($id: ID!) {
+    revokeApiKey(id: $id) {
+      id
+      revokedAt
+    }
+  }
+`;
+
+export const RESEND_EMAIL = gql`
+  mutation ResendEmail($id: ID!) {
+    resendEmail(id: $id) {
+      id
+      status
+      attempts
+      nextAttemptAt
+    }
+  }
+`;
+
+export const RESPOND_TO_REMINDER = gql`
+  mutation RespondToReminder($token: String!, $attending: Boolean!) {
+    respondToReminder(token: $token, attending: $attending) {
+      id
+      status
+      reserveAt
+    }
+  }
+`;
+
+export const SET_NOTIFICATION_CHANNELS = gql`
+  mutation SetNotificationChannels($id: ID!, $channels: [NotificationChannel!]!) {
+    setNotificationChannels(id: $id, channels: $channels) {
+      id
+      notificationChannels
+    }
+  }
+`;
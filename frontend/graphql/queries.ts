@@ -10,10 +10,16 @@ export const GET_RESERVATION_INFO_TODAY = gql`
       totalConfirmedReservation
       totalBigReservation
       totalCanceledReservation
+      totalSeatedReservation
+      totalCompletedReservation
+      totalNoShowReservation
+      noShowRate
+      seatedCovers
       byHours {
         totalReservation
         totalPerson
         totalBigReservation
+        totalSeatedPerson
         startsAt
         endsAt
       }
@@ -47,3 +53,115 @@ export const GET_ALL_RESERVATION_WITH_FILTER = gql`
   ${RESERVATION_FIELDS}
 `;
 
+export const GET_AVAILABILITY = gql`
+  query Availability($date: Time!, $partySize: Int!) {
+    availability(date: $date, partySize: $partySize) {
+      startsAt
+      endsAt
+      remainingCovers
+      remainingReservations
+    }
+  }
+`;
+
+export const GET_RESERVATION_HISTORY = gql`
+  query GetReservationHistory($filter: ReservationFilter!) {
+    getAllReservationWithFilter(filter: $filter) {
+      id
+      history {
+        id
+        type
+        actorRole
+        actorId
+        reason
+        createdAt
+        changes {
+          field
+          before
+          after
+        }
+      }
+    }
+  }
+`;
+
+export const GET_STAFF_USERS = gql`
+  query GetStaffUsers {
+    staffUsers {
+      id
+      username
+      email
+      displayName
+      role
+      disabled
+      pendingInvite
+      totpEnabled
+      createdAt
+    }
+  }
+`;
+
+export const GET_MY_RESERVATIONS = gql`
+  query GetMyReservations {
+    myReservations {
+      id
+      firstName
+      lastName
+      phoneNumber
+      email
+      amount
+      createdAt
+      reserveAt
+      status
+      notes
+    }
+  }
+`;
+
+export const GET_API_KEYS = gql`
+  query GetApiKeys {
+    apiKeys {
+      id
+      name
+      prefix
+      scopes
+      createdBy
+      createdAt
+      lastUsedAt
+      revokedAt
+      usage {
+        operation
+        count
+        lastUsedAt
+      }
+    }
+    apiKeyScopes
+  }
+`;
+
+export const GET_EMAIL_OUTBOX = gql`
+  query GetEmailOutbox($status: EmailStatus!) {
+    emailOutbox(status: $status) {
+      id
+      reservationId
+      event
+      channel
+      recipient
+      status
+      attempts
+      lastError
+      nextAttemptAt
+      createdAt
+      sentAt
+    }
+  }
+`;
+
+export const GET_EMAIL_PREVIEW = gql`
+  query GetEmailPreview($reservationId: ID!, $event: ReservationEventBroadcast!) {
+    previewEmail(reservationId: $reservationId, event: $event) {
+      subject
+      html
+    }
+  }
+`;
@@ -2,31 +2,26 @@ import { useEffect, useState } from "react";
 import { useLazyQuery } from "@apollo/client/react";
 import { GET_RESERVATION_INFO_TODAY } from "@/graphql/queries";
 import { ReservationInfo } from "@/lib/modelTypes";
+import { adminContext, hasSession } from "@/lib/session";
 
 export const useDashboardData = () => {
-  const [token, setToken] = useState<string | null>(null);
+  const [signedIn, setSignedIn] = useState(false);
 
   const [fetch, { data, loading, error, refetch }] = useLazyQuery<{
     getReservationInfoToday: ReservationInfo;
   }>(GET_RESERVATION_INFO_TODAY, { fetchPolicy: "network-only" });
 
   useEffect(() => {
-    const stored = localStorage.getItem("adminToken");
-    if (stored) {
-      try {
-        const { token } = JSON.parse(stored) as { token: string };
-        setToken(token);
-      } catch {}
-    }
+    setSignedIn(hasSession("admin"));
   }, []);
 
   useEffect(() => {
-    if (token) {
+    if (signedIn) {
       fetch({
-        context: { headers: { Authorization: `Bearer ${token}` } },
+        context: adminContext,
       });
     }
-  }, [token, fetch]);
+  }, [signedIn, fetch]);
 
   const info = data?.getReservationInfoToday;
 
@@ -34,6 +29,6 @@ export const useDashboardData = () => {
     info,
     loading,
     error,
-    refetch: () => refetch?.({ context: { headers: { Authorization: `Bearer ${token}` } } }),
+    refetch: () => refetch?.(),
   };
 };
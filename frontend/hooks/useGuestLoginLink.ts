@@ -0,0 +1,34 @@
+import { useState } from "react";
+import { useMutation } from "@apollo/client/react";
+import { useSearchParams } from "next/navigation";
+import { REDEEM_GUEST_LOGIN_LINK } from "@/graphql/mutations";
+import { GuestLoginResponse, Reservation } from "@/lib/modelTypes";
+import { storeSession } from "@/lib/session";
+
+export const useGuestLoginLink = () => {
+  const searchParams = useSearchParams();
+  const linkToken = searchParams.get("token");
+
+  const [reservations, setReservations] = useState<Reservation[] | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  const [redeem, { loading }] = useMutation<{ redeemGuestLoginLink: GuestLoginResponse }>(REDEEM_GUEST_LOGIN_LINK);
+
+  // The link is only redeemed on a click: mail scanners open every link of a
+  // mail, which would use up the single-use token before the guest does.
+  const handleLogin = async () => {
+    if (!linkToken) return;
+    setError(null);
+    try {
+      const { data } = await redeem({ variables: { token: linkToken } });
+      const result = data?.redeemGuestLoginLink;
+      if (!result) throw new Error("Invalid response");
+      storeSession("guest", result);
+      setReservations(result.reservations);
+    } catch (err) {
+      setError(err instanceof Error && err.message ? err.message : "Der Anmeldelink ist ungültig oder abgelaufen.");
+    }
+  };
+
+  return { linkToken, reservations, error, loading, handleLogin };
+};
@@ -1,19 +1,21 @@
 import { useState, useEffect } from "react";
-import { useMutation } from "@apollo/client/react";
+import { useLazyQuery, useMutation } from "@apollo/client/react";
 import { useSearchParams } from "next/navigation";
 import {
   LOGIN_WITH_RESERVATION,
   UPDATE_RESERVATION,
   CANCEL_RESERVATION,
 } from "@/graphql/mutations";
+import { GET_MY_RESERVATIONS } from "@/graphql/queries";
 import { Reservation, ReservationStatus, LoginWithReservationResponse } from "@/lib/modelTypes";
+import { guestContext, hasSession, storeSession } from "@/lib/session";
 
 export const useGuestReservation = () => {
   const searchParams = useSearchParams();
   const reservationId = searchParams.get("id");
 
   const [reservation, setReservation] = useState<Reservation | null>(null);
-  const [token, setToken] = useState<string | null>(null);
+  const [signedIn, setSignedIn] = useState(false);
   const [lastNameInput, setLastNameInput] = useState("");
   const [showAuthModal, setShowAuthModal] = useState(true);
   const [notification, setNotification] = useState<string | null>(null);
@@ -21,21 +23,23 @@ export const useGuestReservation = () => {
   const [login] = useMutation<{loginWithReservation: LoginWithReservationResponse}>(LOGIN_WITH_RESERVATION);
   const [update] = useMutation<{updateReservation: Reservation}>(UPDATE_RESERVATION);
   const [cancel] = useMutation<{cancelReservation: Reservation}>(CANCEL_RESERVATION);
+  const [fetchMine] = useLazyQuery<{myReservations: Reservation[]}>(GET_MY_RESERVATIONS, { fetchPolicy: "network-only" });
 
   useEffect(() => {
-    const stored = localStorage.getItem("userToken");
-    if (stored) {
-      try {
-        const { token: t, expire } = JSON.parse(stored);
-        if (expire > Date.now()) {
-          setToken(t);
-          // Optionally auto-fetch reservation if token is valid
-        } else {
-          localStorage.removeItem("userToken");
+    if (!hasSession("guest")) return;
+    setSignedIn(true);
+    // A guest signed in by email link or with this reservation before does
+    // not need to enter the last name again.
+    fetchMine({ context: guestContext })
+      .then(({ data }) => {
+        const own = data?.myReservations.find((r) => r.id === reservationId);
+        if (own) {
+          setReservation(own);
+          setShowAuthModal(false);
         }
-      } catch {}
-    }
-  }, []);
+      })
+      .catch(() => {});
+  }, [fetchMine, reservationId]);
 
   const showNotification = (msg: string) => {
     setNotification(msg);
@@ -51,8 +55,8 @@ export const useGuestReservation = () => {
       const result = data?.loginWithReservation;
       if (!result || !result.reservation) throw new Error("Invalid response");
 
-      localStorage.setItem("userToken", JSON.stringify({ token: result.token, expire: Date.now() + 24 * 60 * 60 * 1000 }));
-      setToken(result.token);
+      storeSession("guest", result);
+      setSignedIn(true);
       setReservation(result.reservation);
       setShowAuthModal(false);
       showNotification("Erfolgreich angemeldet!");
@@ -62,11 +66,11 @@ export const useGuestReservation = () => {
   };
 
   const handleUpdate = async (field: keyof Reservation, value: string | number | null) => {
-    if (!reservation || !token) return;
+    if (!reservation || !signedIn) return;
     try {
       const { data } = await update({
         variables: { input: { id: reservation.id, [field]: value } },
-        context: { headers: { Authorization: `Bearer ${token}` } },
+        context: guestContext,
       });
       if (data?.updateReservation) {
         setReservation(data.updateReservation);
@@ -77,11 +81,11 @@ export const useGuestReservation = () => {
     }
   };
   const handleCancel = async () => {
-    if (!reservation || !token) return;
+    if (!reservation || !signedIn) return;
     try {
       await cancel({
         variables: { id: reservation.id },
-        context: { headers: { Authorization: `Bearer ${token}` } },
+        context: guestContext,
       });
       setReservation({ ...reservation, status: ReservationStatus.CANCELED });
       showNotification("Reservierung storniert");
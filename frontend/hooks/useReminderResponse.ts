@@ -0,0 +1,32 @@
+import { useState } from "react";
+import { useMutation } from "@apollo/client/react";
+import { useSearchParams } from "next/navigation";
+import { RESPOND_TO_REMINDER } from "@/graphql/mutations";
+import { Reservation } from "@/lib/modelTypes";
+
+export const useReminderResponse = () => {
+  const searchParams = useSearchParams();
+  const reminderToken = searchParams.get("token");
+  const action = searchParams.get("action") === "cancel" ? "cancel" : "confirm";
+
+  const [reservation, setReservation] = useState<Reservation | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  const [respond, { loading }] = useMutation<{ respondToReminder: Reservation }>(RESPOND_TO_REMINDER);
+
+  // The answer is only sent on a click: mail scanners open every link of a
+  // mail, which must not cancel the reservation.
+  const handleRespond = async (attending: boolean) => {
+    if (!reminderToken) return;
+    setError(null);
+    try {
+      const { data } = await respond({ variables: { token: reminderToken, attending } });
+      if (!data?.respondToReminder) throw new Error("Invalid response");
+      setReservation(data.respondToReminder);
+    } catch (err) {
+      setError(err instanceof Error && err.message ? err.message : "Der Link ist ungültig oder abgelaufen.");
+    }
+  };
+
+  return { reminderToken, action, reservation, error, loading, handleRespond };
+};
@@ -12,6 +12,7 @@ import {
   OPEN_RESERVATION,
 } from "@/graphql/mutations";
 import { Reservation } from "@/lib/modelTypes";
+import { adminContext, hasSession } from "@/lib/session";
 
 const QUERY_MAP = {
   "big-tables": { query: GET_BIG_RESERVATION, dataKey: "getBigReservation", useFilter: false },
@@ -24,7 +25,7 @@ const QUERY_MAP = {
 type Slug = keyof typeof QUERY_MAP;
 
 export const useReservations = (slug: string) => {
-  const [token, setToken] = useState<string | null>(null);
+  const [signedIn, setSignedIn] = useState(false);
 
   const config = QUERY_MAP[slug as Slug];
   if (!config) throw new Error("Invalid slug");
@@ -41,28 +42,22 @@ export const useReservations = (slug: string) => {
   const [openReservation] = useMutation(OPEN_RESERVATION);
 
   useEffect(() => {
-    const t = localStorage.getItem("adminToken");
-    if (t) {
-      try {
-        const parsed = JSON.parse(t) as { token: string };
-        setToken(parsed.token);
-      } catch {}
-    }
+    setSignedIn(hasSession("admin"));
   }, []);
 
   useEffect(() => {
-    if (token && config) {
+    if (signedIn && config) {
       const variables = config.useFilter ? { filter: { status: config.status } } : undefined;
       fetchReservations({
         variables,
-        context: { headers: { Authorization: `Bearer ${token}` } },
+        context: adminContext,
       });
     }
-  }, [token, config, fetchReservations]);
+  }, [signedIn, config, fetchReservations]);
 
   const reservations: Reservation[] = data?.[config.dataKey as keyof typeof data] || [];
 
-  const authContext = { context: { headers: { Authorization: `Bearer ${token}` } } };
+  const authContext = { context: adminContext };
 
   return {
     reservations,
@@ -1,12 +1,40 @@
-import { ApolloClient, InMemoryCache, HttpLink, ApolloLink } from '@apollo/client';
+import { ApolloClient, InMemoryCache, HttpLink } from '@apollo/client';
+import { SetContextLink } from '@apollo/client/link/context';
+import { accessToken, refreshSession, sessionOfToken, SessionKind } from '@/lib/session';
+
+// The server rejects expired or revoked access tokens with 403 before the
+// request reaches GraphQL. Such a request is sent again once with a renewed
+// token.
+const fetchWithRefresh: typeof fetch = async (input, init) => {
+  const response = await fetch(input, init);
+  const headers = new Headers(init?.headers);
+  const auth = headers.get('Authorization');
+  if (response.status !== 403 || !auth?.startsWith('Bearer ')) return response;
+
+  const kind = sessionOfToken(auth.slice('Bearer '.length));
+  const token = kind && (await refreshSession(kind));
+  if (!token) return response;
+  headers.set('Authorization', `Bearer ${token}`);
+  return fetch(input, { ...init, headers });
+};
 
 const httpLink = new HttpLink({
   uri: process.env.NEXT_PUBLIC_SERVER_URI + "/query",
-  credentials: 'include' // send cookies if needed
+  credentials: 'include', // send cookies if needed
+  fetch: fetchWithRefresh,
+});
+
+// Operations with a session in their context get its access token.
+const authLink = new SetContextLink(async (prevContext) => {
+  const kind = prevContext.session as SessionKind | undefined;
+  if (!kind) return {};
+  const token = await accessToken(kind);
+  if (!token) return {};
+  return { headers: { ...prevContext.headers, Authorization: `Bearer ${token}` } };
 });
 
 const client = new ApolloClient({
-  link: httpLink,
+  link: authLink.concat(httpLink),
   cache: new InMemoryCache()
 });
 
@@ -1,5 +1,14 @@
+export type AvailableSlot = {
+  startsAt: string; // ISO string
+  endsAt: string; // ISO string
+  remainingCovers?: number | null;
+  remainingReservations?: number | null;
+};
+
 export type LoginWithReservationResponse = {
   token: string;
+  refreshToken: string;
+  expiresAt: string; // ISO string
   reservation?: Reservation;
 };
 
@@ -11,6 +20,7 @@ export type NewReservation = {
   email: string;
   reserveAt: string; // ISO string
   notes?: string | null;
+  notificationChannels?: NotificationChannel[] | null;
 };
 
 export type Reservation = {
@@ -24,6 +34,124 @@ export type Reservation = {
   reserveAt: string; // ISO string
   status: ReservationStatus;
   notes?: string | null;
+  notificationChannels?: NotificationChannel[];
+};
+
+export type ReservationChange = {
+  field: string;
+  before?: string | null;
+  after?: string | null;
+};
+
+export type ReservationEvent = {
+  id: string;
+  type: ReservationEventType;
+  actorRole: string;
+  actorId: string;
+  changes: ReservationChange[];
+  reason?: string | null;
+  createdAt: string; // ISO string
+};
+
+export type AuthPayload = {
+  token: string;
+  refreshToken: string;
+  expiresAt: string; // ISO string
+};
+
+export type EmailStatus = "PENDING" | "SENT" | "FAILED";
+
+export type NotificationChannel = "EMAIL" | "SMS";
+
+export type OutboxEmail = {
+  id: string;
+  reservationId: string;
+  event: string;
+  channel: NotificationChannel;
+  recipient: string;
+  status: EmailStatus;
+  attempts: number;
+  lastError?: string | null;
+  nextAttemptAt: string; // ISO string
+  createdAt: string; // ISO string
+  sentAt?: string | null;
+};
+
+export type EmailPreview = {
+  subject: string;
+  html: string;
+};
+
+export type ApiKeyUsage = {
+  operation: string;
+  count: number;
+  lastUsedAt: string; // ISO string
+};
+
+export type ApiKey = {
+  id: string;
+  name: string;
+  prefix: string;
+  scopes: string[];
+  createdBy?: string | null;
+  createdAt: string; // ISO string
+  lastUsedAt?: string | null;
+  revokedAt?: string | null;
+  usage: ApiKeyUsage[];
+};
+
+export type CreatedApiKey = {
+  apiKey: ApiKey;
+  key: string;
+};
+
+export type TotpEnrollment = {
+  secret: string;
+  provisioningUri: string;
+};
+
+export type TotpChallenge = {
+  token: string;
+  expiresAt: string; // ISO string
+  enrollment?: TotpEnrollment | null;
+};
+
+export type LoginResult = {
+  auth?: AuthPayload | null;
+  challenge?: TotpChallenge | null;
+};
+
+export type TotpLoginResult = {
+  auth: AuthPayload;
+  recoveryCodes: string[];
+};
+
+export type GuestLoginResponse = AuthPayload & {
+  reservations: Reservation[];
+};
+
+export type Session = {
+  id: string;
+  tokenType: string;
+  subject: string;
+  userAgent?: string | null;
+  ipAddress?: string | null;
+  createdAt: string; // ISO string
+  lastSeenAt: string; // ISO string
+  expiresAt: string; // ISO string
+  current: boolean;
+};
+
+export type StaffUser = {
+  id: string;
+  username: string;
+  email: string;
+  displayName: string;
+  role: StaffRole;
+  disabled: boolean;
+  pendingInvite: boolean;
+  totpEnabled: boolean;
+  createdAt: string; // ISO string
 };
 
 export type ReservationEventPayload = {
@@ -49,6 +177,11 @@ export type ReservationInfo = {
   totalBigReservation: number;
   totalConfirmedReservation: number;
   totalCanceledReservation: number;
+  totalSeatedReservation: number;
+  totalCompletedReservation: number;
+  totalNoShowReservation: number;
+  noShowRate: number;
+  seatedCovers: number;
   byHours: ReservationInfoByHour[];
 };
 
@@ -56,6 +189,7 @@ export type ReservationInfoByHour = {
   totalReservation: number;
   totalPerson: number;
   totalBigReservation: number;
+  totalSeatedPerson: number;
   startsAt: string; // ISO string
   endsAt: string; // ISO string
 };
@@ -78,6 +212,23 @@ export enum ReservationEventBroadcast {
   CANCELED = "CANCELED",
   CONFIRMED = "CONFIRMED",
   DECLINED = "DECLINED",
+  SEATED = "SEATED",
+  COMPLETED = "COMPLETED",
+  NO_SHOW = "NO_SHOW",
+  GUEST_LOGIN = "GUEST_LOGIN",
+}
+
+export enum ReservationEventType {
+  CREATED = "CREATED",
+  UPDATED = "UPDATED",
+  STATUS_CHANGED = "STATUS_CHANGED",
+  ATTENDANCE_CONFIRMED = "ATTENDANCE_CONFIRMED",
+}
+
+export enum StaffRole {
+  OWNER = "OWNER",
+  MANAGER = "MANAGER",
+  HOST = "HOST",
 }
 
 export enum ReservationStatus {
@@ -85,4 +236,7 @@ export enum ReservationStatus {
   CONFIRMED = "CONFIRMED",
   CANCELED = "CANCELED",
   DECLINED = "DECLINED",
+  SEATED = "SEATED",
+  COMPLETED = "COMPLETED",
+  NO_SHOW = "NO_SHOW",
 }
@@ -0,0 +1,102 @@
+import { print } from "graphql";
+import { REFRESH_TOKEN } from "@/graphql/mutations";
+import { AuthPayload } from "@/lib/modelTypes";
+
+// A staff member and a guest can be signed in side by side. Each session
+// keeps the short-lived access token, the refresh token that renews it and
+// when the access token expires.
+export type SessionKind = "admin" | "guest";
+
+type StoredSession = { token: string; refreshToken?: string; expire: number };
+
+const storageKeys: Record<SessionKind, string> = { admin: "adminToken", guest: "userToken" };
+
+// Requests pass one of these as their context; the Apollo client then sends
+// the session's access token, renewing it when needed.
+export const adminContext = { session: "admin" as SessionKind };
+export const guestContext = { session: "guest" as SessionKind };
+
+export function storeSession(kind: SessionKind, { token, refreshToken, expiresAt }: AuthPayload) {
+  localStorage.setItem(
+    storageKeys[kind],
+    JSON.stringify({ token, refreshToken, expire: new Date(expiresAt).getTime() })
+  );
+}
+
+export function loadSession(kind: SessionKind): StoredSession | null {
+  const stored = localStorage.getItem(storageKeys[kind]);
+  if (!stored) return null;
+  try {
+    const session = JSON.parse(stored) as StoredSession;
+    return session.token ? session : null;
+  } catch {
+    return null;
+  }
+}
+
+export function clearSession(kind: SessionKind) {
+  localStorage.removeItem(storageKeys[kind]);
+}
+
+// hasSession reports whether the user is signed in: the access token is
+// still valid or can be renewed with the refresh token.
+export function hasSession(kind: SessionKind): boolean {
+  const session = loadSession(kind);
+  return !!session && (session.expire > Date.now() || !!session.refreshToken);
+}
+
+// accessToken returns a token to send, renewing it shortly before it expires.
+export async function accessToken(kind: SessionKind): Promise<string | null> {
+  const session = loadSession(kind);
+  if (!session) return null;
+  if (session.expire - 30_000 > Date.now()) return session.token;
+  return refreshSession(kind);
+}
+
+// sessionOfToken finds the session an access token belongs to.
+export function sessionOfToken(token: string): SessionKind | null {
+  for (const kind of Object.keys(storageKeys) as SessionKind[]) {
+    if (loadSession(kind)?.token === token) return kind;
+  }
+  return null;
+}
+
+const refreshing: Partial<Record<SessionKind, Promise<string | null>>> = {};
+
+// refreshSession renews the access token. A refresh token works only once,
+// so concurrent requests share a single refresh.
+export function refreshSession(kind: SessionKind): Promise<string | null> {
+  if (!refreshing[kind]) {
+    refreshing[kind] = renew(kind).finally(() => {
+      delete refreshing[kind];
+    });
+  }
+  return refreshing[kind];
+}
+
+async function renew(kind: SessionKind): Promise<string | null> {
+  const session = loadSession(kind);
+  if (!session?.refreshToken) {
+    clearSession(kind);
+    return null;
+  }
+  let response: Response;
+  try {
+    // Plain fetch, as the Apollo client itself waits for this token.
+    response = await fetch(process.env.NEXT_PUBLIC_SERVER_URI + "/query", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ query: print(REFRESH_TOKEN), variables: { token: session.refreshToken } }),
+    });
+  } catch {
+    // Offline: keep the session and try again with the next request.
+    return null;
+  }
+  const { data } = (await response.json().catch(() => ({}))) as { data?: { refreshToken?: AuthPayload } };
+  if (!data?.refreshToken) {
+    clearSession(kind);
+    return null;
+  }
+  storeSession(kind, data.refreshToken);
+  return data.refreshToken.token;
+}